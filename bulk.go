@@ -0,0 +1,139 @@
+package sqlserver
+
+import (
+	"reflect"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BulkCopy loads the rows of Create through the TDS bulk copy protocol instead of INSERT statements,
+// db.Clauses(sqlserver.BulkCopy{Tablock: true}).CreateInBatches(&users, 10000)
+//
+// bulk copy doesn't return the values generated by the database, structs with such fields, like an
+// identity primary key, are created with INSERT statements to back-fill them and save their
+// associations. go-mssqldb doesn't support KEEPIDENTITY, rows having explicit identity values are
+// created with INSERT statements too, KeepIdentity is always implied
+type BulkCopy struct {
+	Tablock          bool
+	CheckConstraints bool
+	FireTriggers     bool
+	KeepNulls        bool
+	KeepIdentity     bool
+	BatchSize        int
+}
+
+func (BulkCopy) Name() string {
+	return "BULK COPY"
+}
+
+func (BulkCopy) Build(clause.Builder) {
+}
+
+func (bulkCopy BulkCopy) MergeClause(c *clause.Clause) {
+	c.Expression = bulkCopy
+}
+
+func (bulkCopy BulkCopy) options() mssql.BulkOptions {
+	return mssql.BulkOptions{
+		Tablock:          bulkCopy.Tablock,
+		CheckConstraints: bulkCopy.CheckConstraints,
+		FireTriggers:     bulkCopy.FireTriggers,
+		KeepNulls:        bulkCopy.KeepNulls,
+		RowsPerBatch:     bulkCopy.BatchSize,
+	}
+}
+
+// bulkCopyOf returns the bulk copy options to use for values, if any
+func bulkCopyOf(db *gorm.DB, values clause.Values) (bulkCopy BulkCopy, ok bool) {
+	if db.DryRun || db.Error != nil || db.Statement.Schema == nil || len(values.Columns) == 0 {
+		return
+	}
+
+	if c, exists := db.Statement.Clauses["BULK COPY"]; exists {
+		bulkCopy, ok = c.Expression.(BulkCopy)
+	} else if dialector, isDialector := db.Dialector.(*Dialector); isDialector && dialector.BulkCopyThreshold > 0 && len(values.Values) >= dialector.BulkCopyThreshold {
+		bulkCopy, ok = dialector.BulkCopyOptions, true
+	}
+
+	if !ok {
+		return
+	}
+
+	// the generated values, including the identity, must be read back into the created structs
+	if len(createReturningFields(db.Statement.Schema)) > 0 && isStructValue(db.Statement.ReflectValue) {
+		return bulkCopy, false
+	}
+
+	// explicit identity values would be replaced by the server
+	if field := db.Statement.Schema.PrioritizedPrimaryField; field != nil && field.AutoIncrement {
+		for _, column := range values.Columns {
+			if column.Name == field.DBName {
+				return bulkCopy, false
+			}
+		}
+	}
+	return
+}
+
+// isStructValue returns whether value is a struct, or a slice or an array of structs
+func isStructValue(value reflect.Value) bool {
+	valueType := value.Type()
+	if valueType.Kind() == reflect.Slice || valueType.Kind() == reflect.Array {
+		valueType = valueType.Elem()
+	}
+
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	return valueType.Kind() == reflect.Struct
+}
+
+// quotedTable returns the quoted statement table, qualified by its schema
+func quotedTable(stmt *gorm.Statement) string {
+	if stmt.TableExpr != nil {
+		return stmt.TableExpr.SQL
+	}
+	return stmt.Quote(stmt.Table)
+}
+
+func bulkCopyCreate(db *gorm.DB, bulkCopy BulkCopy, values clause.Values) {
+	var (
+		columns = make([]string, 0, len(values.Columns))
+		ctx     = db.Statement.Context
+	)
+
+	for _, column := range values.Columns {
+		columns = append(columns, column.Name)
+	}
+
+	// the copy statement has to be executed on a single connection
+	transactional(db, func() {
+		stmt, err := db.Statement.ConnPool.PrepareContext(ctx, mssql.CopyIn(quotedTable(db.Statement), bulkCopy.options(), columns...))
+		if db.AddError(err) != nil {
+			return
+		}
+		defer stmt.Close()
+
+		row := make([]interface{}, len(columns))
+		for _, value := range values.Values {
+			for idx := range columns {
+				// DEFAULT placeholders, the server applies the column default to NULLs unless KeepNulls is set
+				if _, ok := value[idx].(clause.Expr); ok {
+					row[idx] = nil
+				} else {
+					row[idx] = value[idx]
+				}
+			}
+
+			if _, err := stmt.ExecContext(ctx, row...); db.AddError(err) != nil {
+				return
+			}
+		}
+
+		if result, err := stmt.ExecContext(ctx); db.AddError(err) == nil {
+			db.RowsAffected, _ = result.RowsAffected()
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package sqlserver
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+type bulkLog struct {
+	Code    string `gorm:"primaryKey;size:32"`
+	Message string
+}
+
+type bulkUser struct {
+	ID   uint
+	Name string
+}
+
+func TestBulkCopyCreate(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db := openFake(t, Config{DefaultSchema: "sales", BulkCopyThreshold: 2}, pool)
+
+	logs := []bulkLog{{Code: "a", Message: "created"}, {Code: "b", Message: "updated"}}
+	if err := db.Create(&logs).Error; err != nil {
+		t.Fatalf("failed to bulk copy, got error %v", err)
+	}
+
+	queries := pool.recorded()
+	if len(queries) != 3 || queries[0] != "BEGIN TRANSACTION" || queries[2] != "COMMIT TRANSACTION" {
+		t.Fatalf("expects a single bulk copy in the create transaction, got %v", queries)
+	}
+
+	if !strings.HasPrefix(queries[1], "INSERTBULK ") || !strings.Contains(queries[1], `"TableName":"\"sales\".\"bulk_logs\""`) ||
+		!strings.Contains(queries[1], `"ColumnsName":["code","message"]`) {
+		t.Errorf("expects the rows to be copied into the schema qualified table, got %v", queries[1])
+	}
+
+	if err := db.Create(&[]bulkLog{{Code: "c", Message: "deleted"}}).Error; err != nil {
+		t.Fatalf("failed to create, got error %v", err)
+	}
+
+	if queries := pool.recorded(); len(queries) != 3 || !strings.HasPrefix(queries[1], `INSERT INTO "sales"."bulk_logs"`) {
+		t.Errorf("expects the rows below the threshold to be inserted, got %v", queries)
+	}
+}
+
+func TestBulkCopyBackFillsGeneratedValues(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db := openFake(t, Config{}, pool)
+
+	pool.returns([]string{"id"}, [][]driver.Value{{int64(1)}, {int64(2)}})
+
+	users := []bulkUser{{Name: "a"}, {Name: "b"}}
+	if err := db.Clauses(BulkCopy{Tablock: true}).Create(&users).Error; err != nil {
+		t.Fatalf("failed to create, got error %v", err)
+	}
+
+	if queries := pool.recorded(); len(queries) != 3 || !strings.HasPrefix(queries[1], `INSERT INTO "bulk_users" ("name") OUTPUT INSERTED."id"`) {
+		t.Errorf("expects the rows generating their identity to be inserted, got %v", queries)
+	}
+
+	if users[0].ID != 1 || users[1].ID != 2 {
+		t.Errorf("expects the identities to be back-filled, got %v and %v", users[0].ID, users[1].ID)
+	}
+
+	pool.returns([]string{"id"}, [][]driver.Value{{int64(10)}, {int64(11)}})
+
+	users = []bulkUser{{ID: 10, Name: "c"}, {ID: 11, Name: "d"}}
+	if err := db.Clauses(BulkCopy{Tablock: true}).Create(&users).Error; err != nil {
+		t.Fatalf("failed to create, got error %v", err)
+	}
+
+	if queries := pool.recorded(); len(queries) != 3 || !strings.HasPrefix(queries[1], `SET IDENTITY_INSERT "bulk_users" ON;INSERT INTO "bulk_users" ("name","id")`) {
+		t.Errorf("expects the explicit identities to be kept, got %v", queries)
+	}
+}
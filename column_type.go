@@ -0,0 +1,80 @@
+package sqlserver
+
+import (
+	"database/sql"
+)
+
+// ColumnType column metadata read from sys.columns, returned by Migrator.ColumnTypes
+type ColumnType struct {
+	name               string
+	dataType           string
+	maxLength          int64
+	precision          int64
+	scale              int64
+	nullable           bool
+	collation          sql.NullString
+	identity           bool
+	identitySeed       sql.NullInt64
+	identityIncrement  sql.NullInt64
+	computed           bool
+	computedDefinition sql.NullString
+	defaultName        sql.NullString
+	defaultDefinition  sql.NullString
+}
+
+func (ct ColumnType) Name() string {
+	return ct.name
+}
+
+// DatabaseTypeName returns the type name, rowversion for the timestamp synonym returned by TYPE_NAME
+func (ct ColumnType) DatabaseTypeName() string {
+	if ct.dataType == "timestamp" {
+		return "rowversion"
+	}
+	return ct.dataType
+}
+
+// Length returns the length in characters of string types and in bytes of binary types, -1 for MAX
+func (ct ColumnType) Length() (length int64, ok bool) {
+	switch ct.dataType {
+	case "nvarchar", "nchar":
+		if ct.maxLength == -1 {
+			return -1, true
+		}
+		return ct.maxLength / 2, true
+	case "varchar", "char", "varbinary", "binary":
+		return ct.maxLength, true
+	}
+	return 0, false
+}
+
+func (ct ColumnType) DecimalSize() (precision int64, scale int64, ok bool) {
+	switch ct.dataType {
+	case "decimal", "numeric":
+		return ct.precision, ct.scale, true
+	}
+	return 0, 0, false
+}
+
+func (ct ColumnType) Nullable() (nullable bool, ok bool) {
+	return ct.nullable, true
+}
+
+// Identity returns the seed and increment of IDENTITY columns
+func (ct ColumnType) Identity() (seed int64, increment int64, ok bool) {
+	return ct.identitySeed.Int64, ct.identityIncrement.Int64, ct.identity
+}
+
+// Computed returns the definition of computed columns
+func (ct ColumnType) Computed() (definition string, ok bool) {
+	return ct.computedDefinition.String, ct.computed
+}
+
+func (ct ColumnType) Collation() (collation string, ok bool) {
+	return ct.collation.String, ct.collation.Valid
+}
+
+// DefaultConstraint returns the name and the expression of the column default constraint
+func (ct ColumnType) DefaultConstraint() (name string, definition string, ok bool) {
+	return ct.defaultName.String, ct.defaultDefinition.String, ct.defaultName.Valid
+}
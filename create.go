@@ -1,13 +1,44 @@
 package sqlserver
 
 import (
+	"database/sql"
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/callbacks"
 	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
+const (
+	// maxBindVars maximum number of parameters of a single request, sp_executesql takes 2 of the 2100
+	// parameters of SQL Server for the statement and its parameter definitions
+	maxBindVars = 2098
+	// maxInsertRows maximum number of rows of a table value constructor
+	maxInsertRows = 1000
+)
+
+// UpsertStrategy statements used by Create with clause.OnConflict
+type UpsertStrategy int
+
+const (
+	// UpsertMerge upserts with MERGE, see MergeCreate
+	UpsertMerge UpsertStrategy = iota
+	// UpsertUpdateInsert upserts with UPDATE and INSERT ... WHERE NOT EXISTS, see UpdateInsertCreate
+	UpsertUpdateInsert
+)
+
+func upsertStrategyOf(db *gorm.DB) UpsertStrategy {
+	if dialector, ok := db.Dialector.(*Dialector); ok {
+		return dialector.UpsertStrategy
+	}
+	return UpsertMerge
+}
+
 func Create(db *gorm.DB) {
 	if db.Statement.Schema != nil && !db.Statement.Unscoped {
 		for _, c := range db.Statement.Schema.CreateClauses {
@@ -15,7 +46,14 @@ func Create(db *gorm.DB) {
 		}
 	}
 
+	var (
+		elements []int
+		keys     []*schema.Field
+	)
 	if db.Statement.SQL.String() == "" {
+		omitRowVersion(db)
+		omitPeriodColumns(db)
+
 		var (
 			values                  = callbacks.ConvertToCreateValues(db.Statement)
 			c                       = db.Statement.Clauses["ON CONFLICT"]
@@ -23,191 +61,469 @@ func Create(db *gorm.DB) {
 		)
 
 		if hasConflict {
-			if len(db.Statement.Schema.PrimaryFields) > 0 {
-				columnsMap := map[string]bool{}
-				for _, column := range values.Columns {
-					columnsMap[column.Name] = true
-				}
+			var err error
+			if onConflict.Columns, err = conflictColumns(db.Statement, onConflict, values); db.AddError(err) != nil {
+				return
+			}
+			hasConflict = len(onConflict.Columns) > 0
+			keys = conflictKeys(db.Statement, onConflict.Columns)
+		}
+
+		if bulkCopy, ok := bulkCopyOf(db, values); ok && !hasConflict {
+			bulkCopyCreate(db, bulkCopy, values)
+			return
+		}
+
+		// UPDATE and INSERT bind the values twice, and must run in the same transaction
+		upsert, maxVars := hasConflict && upsertStrategyOf(db) == UpsertUpdateInsert, maxBindVars
+		if upsert {
+			maxVars /= 2
+		}
 
-				for _, field := range db.Statement.Schema.PrimaryFields {
-					if _, ok := columnsMap[field.DBName]; !ok {
-						hasConflict = false
+		batches := createBatches(db, hasConflict, keys, values, maxVars)
+		if (len(batches) > 1 || upsert) && !db.DryRun && db.Error == nil {
+			transactional(db, func() {
+				for _, batch := range batches {
+					db.Statement.SQL.Reset()
+					db.Statement.Vars = nil
+					buildCreate(db, hasConflict, onConflict, batch)
+					execCreate(db, batch.elements, keys)
+					if db.Error != nil {
+						return
 					}
 				}
-			} else {
-				hasConflict = false
-			}
+			})
+			return
 		}
 
-		if hasConflict {
-			MergeCreate(db, onConflict, values)
-		} else {
-			setIdentityInsert := false
-
-			if db.Statement.Schema != nil {
-				if field := db.Statement.Schema.PrioritizedPrimaryField; field != nil && field.AutoIncrement {
-					switch db.Statement.ReflectValue.Kind() {
-					case reflect.Struct:
-						_, isZero := field.ValueOf(db.Statement.ReflectValue)
-						setIdentityInsert = !isZero
-					case reflect.Slice, reflect.Array:
-						for i := 0; i < db.Statement.ReflectValue.Len(); i++ {
-							obj := db.Statement.ReflectValue.Index(i)
-							if reflect.Indirect(obj).Kind() == reflect.Struct {
-								_, isZero := field.ValueOf(db.Statement.ReflectValue.Index(i))
-								setIdentityInsert = !isZero
-							}
-							break
-						}
-					}
+		for _, batch := range batches {
+			buildCreate(db, hasConflict, onConflict, batch)
+		}
+		elements = batches[0].elements
+	}
 
-					if setIdentityInsert {
-						db.Statement.WriteString("SET IDENTITY_INSERT ")
-						db.Statement.WriteQuoted(db.Statement.Table)
-						db.Statement.WriteString(" ON;")
-					}
+	if !db.DryRun && db.Error == nil {
+		if elements == nil && (db.Statement.ReflectValue.Kind() == reflect.Slice || db.Statement.ReflectValue.Kind() == reflect.Array) {
+			elements = returningElements(db, allElements(db.Statement.ReflectValue.Len()))
+		}
+		execCreate(db, elements, keys)
+	}
+}
+
+// createBatch rows created by a single statement, elements are the indexes of the slice elements
+// receiving the OUTPUT INSERTED rows, nil when creating a struct
+type createBatch struct {
+	values         clause.Values
+	elements       []int
+	identityInsert bool
+}
+
+// createBatches splits values into the batches of statements to execute, rows with explicit
+// primary keys are inserted apart from the rows generating them
+func createBatches(db *gorm.DB, hasConflict bool, keys []*schema.Field, values clause.Values, maxVars int) (batches []createBatch) {
+	var groups []createBatch
+	switch db.Statement.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		groups = []createBatch{{values: values, elements: allElements(len(values.Values))}}
+	default:
+		groups = []createBatch{{values: values}}
+	}
+
+	if !hasConflict {
+		groups = primaryKeyGroups(db, groups[0])
+	}
+
+	for _, group := range groups {
+		offset := 0
+		for _, batchValues := range splitValues(group.values, maxVars) {
+			batch := createBatch{values: batchValues, identityInsert: group.identityInsert}
+			if group.elements != nil {
+				batch.elements = group.elements[offset : offset+len(batchValues.Values)]
+				if hasConflict && keys == nil {
+					batch.elements = returningElements(db, batch.elements)
 				}
 			}
+			batches = append(batches, batch)
+			offset += len(batchValues.Values)
+		}
+	}
+	return
+}
 
-			db.Statement.AddClauseIfNotExists(clause.Insert{})
-			db.Statement.Build("INSERT")
-			db.Statement.WriteByte(' ')
+// primaryKeyGroups splits group into the rows with explicit primary keys, with IDENTITY_INSERT ON for
+// identity columns, and the rows generating them by the identity or the default of the column, such
+// as NEWSEQUENTIALID(), inserted without the primary key column instead of NULL
+func primaryKeyGroups(db *gorm.DB, group createBatch) []createBatch {
+	if db.Statement.Schema == nil {
+		return []createBatch{group}
+	}
 
-			db.Statement.AddClause(values)
-			if values, ok := db.Statement.Clauses["VALUES"].Expression.(clause.Values); ok {
-				if len(values.Columns) > 0 {
-					db.Statement.WriteByte('(')
-					for idx, column := range values.Columns {
-						if idx > 0 {
-							db.Statement.WriteByte(',')
-						}
-						db.Statement.WriteQuoted(column)
-					}
-					db.Statement.WriteByte(')')
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	if field == nil || !(field.AutoIncrement || hasDatabaseDefault(field)) {
+		return []createBatch{group}
+	}
+
+	columnIdx := -1
+	for idx, column := range group.values.Columns {
+		if column.Name == field.DBName {
+			columnIdx = idx
+		}
+	}
+	if columnIdx == -1 {
+		return []createBatch{group}
+	}
 
-					outputInserted(db)
+	if group.elements == nil {
+		if db.Statement.ReflectValue.Kind() == reflect.Struct {
+			_, isZero := field.ValueOf(db.Statement.ReflectValue)
+			group.identityInsert = !isZero && field.AutoIncrement
+		}
+		return []createBatch{group}
+	}
 
-					db.Statement.WriteString(" VALUES ")
+	explicit := createBatch{values: clause.Values{Columns: group.values.Columns}, identityInsert: field.AutoIncrement}
+	generated := createBatch{values: clause.Values{Columns: make([]clause.Column, 0, len(group.values.Columns)-1)}}
+	generated.values.Columns = append(generated.values.Columns, group.values.Columns[:columnIdx]...)
+	generated.values.Columns = append(generated.values.Columns, group.values.Columns[columnIdx+1:]...)
 
-					for idx, value := range values.Values {
-						if idx > 0 {
-							db.Statement.WriteByte(',')
-						}
+	for idx, element := range group.elements {
+		obj := db.Statement.ReflectValue.Index(element)
+		if reflect.Indirect(obj).Kind() != reflect.Struct {
+			return []createBatch{group}
+		}
 
-						db.Statement.WriteByte('(')
-						db.Statement.AddVar(db.Statement, value...)
-						db.Statement.WriteByte(')')
+		if _, isZero := field.ValueOf(obj); isZero {
+			row := make([]interface{}, 0, len(generated.values.Columns))
+			row = append(row, group.values.Values[idx][:columnIdx]...)
+			row = append(row, group.values.Values[idx][columnIdx+1:]...)
+			generated.values.Values = append(generated.values.Values, row)
+			generated.elements = append(generated.elements, element)
+		} else {
+			explicit.values.Values = append(explicit.values.Values, group.values.Values[idx])
+			explicit.elements = append(explicit.elements, element)
+		}
+	}
+
+	if len(generated.elements) == 0 {
+		return []createBatch{explicit}
+	} else if len(explicit.elements) == 0 {
+		return []createBatch{generated}
+	}
+	return []createBatch{explicit, generated}
+}
+
+// hasDatabaseDefault returns whether the default of field is an expression evaluated by the database,
+// the zero values of such fields are created as NULL by gorm when other rows have values
+func hasDatabaseDefault(field *schema.Field) bool {
+	return field.HasDefaultValue && field.DefaultValueInterface == nil && field.DefaultValue != "" && field.DefaultValue != "(-)"
+}
+
+func allElements(length int) []int {
+	elements := make([]int, length)
+	for i := range elements {
+		elements[i] = i
+	}
+	return elements
+}
+
+// returningElements returns the elements receiving the OUTPUT rows of upserts whose conflict columns
+// can't be matched to the elements, guessing only the elements without primary key are returned
+// when there are some
+func returningElements(db *gorm.DB, elements []int) []int {
+	if db.Statement.Schema == nil || db.Statement.Schema.PrioritizedPrimaryField == nil {
+		return elements
+	}
+
+	var nonePrimaryValues []int
+	for _, element := range elements {
+		obj := db.Statement.ReflectValue.Index(element)
+		if reflect.Indirect(obj).Kind() != reflect.Struct {
+			return elements
+		}
+
+		if _, isZero := db.Statement.Schema.PrioritizedPrimaryField.ValueOf(obj); isZero {
+			nonePrimaryValues = append(nonePrimaryValues, element)
+		}
+	}
+
+	if len(nonePrimaryValues) == 0 {
+		return elements
+	}
+	return nonePrimaryValues
+}
+
+// splitValues splits values into batches respecting the bind variables limit and the rows limit of
+// the table value constructor
+func splitValues(values clause.Values, maxVars int) []clause.Values {
+	batchSize := maxInsertRows
+	if len(values.Columns) > 0 && maxVars/len(values.Columns) < batchSize {
+		batchSize = maxVars / len(values.Columns)
+	}
+
+	if len(values.Values) <= batchSize {
+		return []clause.Values{values}
+	}
+
+	batches := make([]clause.Values, 0, len(values.Values)/batchSize+1)
+	for i := 0; i < len(values.Values); i += batchSize {
+		end := i + batchSize
+		if end > len(values.Values) {
+			end = len(values.Values)
+		}
+		batches = append(batches, clause.Values{Columns: values.Columns, Values: values.Values[i:end]})
+	}
+	return batches
+}
+
+func buildCreate(db *gorm.DB, hasConflict bool, onConflict clause.OnConflict, batch createBatch) {
+	values := batch.values
+	if hasConflict && upsertStrategyOf(db) == UpsertUpdateInsert {
+		UpdateInsertCreate(db, onConflict, values)
+	} else if hasConflict {
+		MergeCreate(db, onConflict, values)
+	} else {
+		if batch.identityInsert {
+			db.Statement.WriteString("SET IDENTITY_INSERT ")
+			db.Statement.WriteQuoted(clause.Table{Name: clause.CurrentTable})
+			db.Statement.WriteString(" ON;")
+		}
+
+		db.Statement.AddClauseIfNotExists(clause.Insert{})
+		db.Statement.Build("INSERT")
+		db.Statement.WriteByte(' ')
+
+		db.Statement.AddClause(values)
+		if values, ok := db.Statement.Clauses["VALUES"].Expression.(clause.Values); ok {
+			if len(values.Columns) > 0 {
+				db.Statement.WriteByte('(')
+				for idx, column := range values.Columns {
+					if idx > 0 {
+						db.Statement.WriteByte(',')
 					}
+					db.Statement.WriteQuoted(column)
+				}
+				db.Statement.WriteByte(')')
+
+				outputInserted(db, nil)
+
+				db.Statement.WriteString(" VALUES ")
 
-					db.Statement.WriteString(";")
-				} else {
-					db.Statement.WriteString("DEFAULT VALUES;")
+				for idx, value := range values.Values {
+					if idx > 0 {
+						db.Statement.WriteByte(',')
+					}
+
+					db.Statement.WriteByte('(')
+					db.Statement.AddVar(db.Statement, value...)
+					db.Statement.WriteByte(')')
 				}
-			}
 
-			if setIdentityInsert {
-				db.Statement.WriteString("SET IDENTITY_INSERT ")
-				db.Statement.WriteQuoted(db.Statement.Table)
-				db.Statement.WriteString(" OFF;")
+				db.Statement.WriteString(";")
+			} else {
+				db.Statement.WriteString("DEFAULT VALUES;")
 			}
 		}
-	}
 
-	if !db.DryRun && db.Error == nil {
-		if len(db.Statement.Schema.FieldsWithDefaultDBValue) > 0 {
-			rows, err := db.Statement.ConnPool.QueryContext(db.Statement.Context, db.Statement.SQL.String(), db.Statement.Vars...)
+		if batch.identityInsert {
+			db.Statement.WriteString("SET IDENTITY_INSERT ")
+			db.Statement.WriteQuoted(clause.Table{Name: clause.CurrentTable})
+			db.Statement.WriteString(" OFF;")
+		}
+	}
+}
 
-			if err == nil {
-				defer rows.Close()
+// execCreate executes the statement, scanning the OUTPUT rows into elements, in order, or into the
+// elements with the same conflict keys when keys are given
+func execCreate(db *gorm.DB, elements []int, keys []*schema.Field) {
+	if returning := createReturningFields(db.Statement.Schema); len(returning) > 0 {
+		rows, err := db.Statement.ConnPool.QueryContext(db.Statement.Context, db.Statement.SQL.String(), db.Statement.Vars...)
 
-				values := make([]interface{}, len(db.Statement.Schema.FieldsWithDefaultDBValue))
+		if err == nil {
+			defer rows.Close()
 
-				switch db.Statement.ReflectValue.Kind() {
-				case reflect.Slice, reflect.Array:
-					var hasPrimaryValues, nonePrimaryValues []int
-					for i := 0; i < db.Statement.ReflectValue.Len(); i++ {
-						obj := db.Statement.ReflectValue.Index(i)
-						if reflect.Indirect(obj).Kind() != reflect.Struct {
-							return
-						}
+			values := make([]interface{}, len(returning))
 
-						if _, isZero := db.Statement.Schema.PrioritizedPrimaryField.ValueOf(obj); isZero {
-							nonePrimaryValues = append(nonePrimaryValues, i)
-						} else {
-							hasPrimaryValues = append([]int{i}, hasPrimaryValues...)
-						}
+			switch db.Statement.ReflectValue.Kind() {
+			case reflect.Slice, reflect.Array:
+				rowsAffected := 0
+				for _, element := range elements {
+					if reflect.Indirect(db.Statement.ReflectValue.Index(element)).Kind() != reflect.Struct {
+						return
 					}
+				}
 
+				if len(keys) > 0 {
+					db.RowsAffected += int64(scanByKeys(db, rows, returning, keys, elements))
+					break
+				}
+
+				// UpdateInsertCreate returns the updated and the inserted rows as separate result sets
+				for hasResultSet := true; hasResultSet; hasResultSet = rows.NextResultSet() {
 					for rows.Next() {
-						if int(db.RowsAffected) < len(nonePrimaryValues) {
-							for idx, field := range db.Statement.Schema.FieldsWithDefaultDBValue {
-								fieldValue := field.ReflectValueOf(db.Statement.ReflectValue.Index(nonePrimaryValues[db.RowsAffected]))
+						if rowsAffected < len(elements) {
+							for idx, field := range returning {
+								fieldValue := field.ReflectValueOf(db.Statement.ReflectValue.Index(elements[rowsAffected]))
 								values[idx] = fieldValue.Addr().Interface()
 							}
 
 							db.AddError(rows.Scan(values...))
 						}
-						db.RowsAffected++
-					}
-				case reflect.Struct:
-					for idx, field := range db.Statement.Schema.FieldsWithDefaultDBValue {
-						values[idx] = field.ReflectValueOf(db.Statement.ReflectValue).Addr().Interface()
+						rowsAffected++
 					}
+				}
+				db.RowsAffected += int64(rowsAffected)
+			case reflect.Struct:
+				for idx, field := range returning {
+					values[idx] = field.ReflectValueOf(db.Statement.ReflectValue).Addr().Interface()
+				}
 
+				for hasResultSet := true; hasResultSet; hasResultSet = rows.NextResultSet() {
 					if rows.Next() {
 						db.RowsAffected++
 						db.AddError(rows.Scan(values...))
+						break
 					}
 				}
+			}
 
-				db.AddError(rows.Err())
+			db.AddError(rows.Err())
+		} else {
+			db.AddError(err)
+		}
+	} else {
+		result, err := db.Statement.ConnPool.ExecContext(db.Statement.Context, db.Statement.SQL.String(), db.Statement.Vars...)
+		if db.AddError(err) == nil {
+			rowsAffected, _ := result.RowsAffected()
+			db.RowsAffected += rowsAffected
+		}
+	}
+}
+
+// conflictColumns returns the columns matching existing rows, the OnConflict columns, which must all be
+// created, or else the primary keys or the first unique index created entirely. The rows generating
+// their primary key, which the OnConflict columns of UpdateAll and of the associations default to,
+// can't conflict on it and are inserted without matching existing rows
+func conflictColumns(stmt *gorm.Statement, onConflict clause.OnConflict, values clause.Values) ([]clause.Column, error) {
+	columnsMap := map[string]bool{}
+	for _, column := range values.Columns {
+		columnsMap[column.Name] = true
+	}
+
+	covered := func(columns []clause.Column) bool {
+		for _, column := range columns {
+			if !columnsMap[column.Name] {
+				return false
+			}
+		}
+		return len(columns) > 0
+	}
+
+	if len(onConflict.Columns) > 0 {
+		var generated bool
+		for _, column := range onConflict.Columns {
+			if columnsMap[column.Name] {
+				continue
+			} else if isGeneratedPrimaryKey(stmt, column.Name) {
+				generated = true
 			} else {
-				db.AddError(err)
+				return nil, fmt.Errorf("%w: %s", ErrMissingConflictColumns, column.Name)
 			}
-		} else {
-			result, err := db.Statement.ConnPool.ExecContext(db.Statement.Context, db.Statement.SQL.String(), db.Statement.Vars...)
-			if db.AddError(err) == nil {
-				db.RowsAffected, _ = result.RowsAffected()
+		}
+
+		if generated {
+			return nil, nil
+		}
+		return onConflict.Columns, nil
+	} else if stmt.Schema == nil {
+		return nil, nil
+	}
+
+	var candidates [][]clause.Column
+	primaryColumns := make([]clause.Column, 0, len(stmt.Schema.PrimaryFields))
+	for _, field := range stmt.Schema.PrimaryFields {
+		primaryColumns = append(primaryColumns, clause.Column{Name: field.DBName})
+	}
+	candidates = append(candidates, primaryColumns)
+
+	indexes := stmt.Schema.ParseIndexes()
+	names := make([]string, 0, len(indexes))
+	for name, index := range indexes {
+		if index.Class == "UNIQUE" && index.Where == "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		columns := make([]clause.Column, 0, len(indexes[name].Fields))
+		for _, option := range indexes[name].Fields {
+			if option.Field == nil {
+				columns = nil
+				break
 			}
+			columns = append(columns, clause.Column{Name: option.DBName})
 		}
+		candidates = append(candidates, columns)
 	}
-}
 
-func MergeCreate(db *gorm.DB, onConflict clause.OnConflict, values clause.Values) {
-	db.Statement.WriteString("MERGE INTO ")
-	db.Statement.WriteQuoted(db.Statement.Table)
-	db.Statement.WriteString(" USING (VALUES")
-	for idx, value := range values.Values {
-		if idx > 0 {
-			db.Statement.WriteByte(',')
+	for _, field := range stmt.Schema.Fields {
+		if field.Unique && field.DBName != "" {
+			candidates = append(candidates, []clause.Column{{Name: field.DBName}})
 		}
+	}
 
-		db.Statement.WriteByte('(')
-		db.Statement.AddVar(db.Statement, value...)
-		db.Statement.WriteByte(')')
+	for _, columns := range candidates {
+		if covered(columns) {
+			return columns, nil
+		}
 	}
+	return nil, nil
+}
 
-	db.Statement.WriteString(") AS excluded (")
-	for idx, column := range values.Columns {
-		if idx > 0 {
-			db.Statement.WriteByte(',')
+// isGeneratedPrimaryKey returns whether column is a primary key generated by the database
+func isGeneratedPrimaryKey(stmt *gorm.Statement, column string) bool {
+	if stmt.Schema == nil {
+		return false
+	}
+
+	field := stmt.Schema.LookUpField(column)
+	return field != nil && field.PrimaryKey && (field.AutoIncrement || hasDatabaseDefault(field))
+}
+
+// MergeCreate creates values with MERGE ... WITH (HOLDLOCK), matching existing rows on the
+// OnConflict columns, the primary keys when not given
+func MergeCreate(db *gorm.DB, onConflict clause.OnConflict, values clause.Values) {
+	if len(onConflict.Columns) == 0 {
+		for _, field := range db.Statement.Schema.PrimaryFields {
+			onConflict.Columns = append(onConflict.Columns, clause.Column{Name: field.DBName})
 		}
-		db.Statement.WriteQuoted(column.Name)
 	}
-	db.Statement.WriteString(") ON ")
+
+	db.Statement.WriteString("MERGE INTO ")
+	db.Statement.WriteQuoted(clause.Table{Name: clause.CurrentTable})
+	db.Statement.WriteString(" WITH (HOLDLOCK) USING ")
+	writeExcluded(db, values)
+	db.Statement.WriteString(" ON ")
 
 	var where clause.Where
-	for _, field := range db.Statement.Schema.PrimaryFields {
+	for _, column := range onConflict.Columns {
 		where.Exprs = append(where.Exprs, clause.Eq{
-			Column: clause.Column{Table: db.Statement.Table, Name: field.DBName},
-			Value:  clause.Column{Table: "excluded", Name: field.DBName},
+			Column: clause.Column{Table: clause.CurrentTable, Name: column.Name},
+			Value:  clause.Column{Table: "excluded", Name: column.Name},
 		})
 	}
 	where.Build(db.Statement)
 
-	if len(onConflict.DoUpdates) > 0 {
-		db.Statement.WriteString(" WHEN MATCHED THEN UPDATE SET ")
-		onConflict.DoUpdates.Build(db.Statement)
+	doUpdates := conflictUpdates(onConflict)
+	if !onConflict.DoNothing && len(doUpdates) > 0 {
+		db.Statement.WriteString(" WHEN MATCHED")
+		if len(onConflict.Where.Exprs) > 0 {
+			db.Statement.WriteString(" AND (")
+			onConflict.Where.Build(db.Statement)
+			db.Statement.WriteByte(')')
+		}
+		db.Statement.WriteString(" THEN UPDATE SET ")
+		doUpdates.Build(db.Statement)
 	}
 
 	db.Statement.WriteString(" WHEN NOT MATCHED THEN INSERT (")
@@ -240,14 +556,154 @@ func MergeCreate(db *gorm.DB, onConflict clause.OnConflict, values clause.Values
 	}
 
 	db.Statement.WriteString(")")
-	outputInserted(db)
+	outputInserted(db, conflictKeys(db.Statement, onConflict.Columns))
 	db.Statement.WriteString(";")
 }
 
-func outputInserted(db *gorm.DB) {
-	if db.Statement.Schema != nil && len(db.Statement.Schema.FieldsWithDefaultDBValue) > 0 {
+// conflictUpdates returns the assignments of matched rows, UpdateAll doesn't assign the conflict
+// columns which are equal already
+func conflictUpdates(onConflict clause.OnConflict) clause.Set {
+	if onConflict.DoNothing || !onConflict.UpdateAll {
+		return onConflict.DoUpdates
+	}
+
+	doUpdates := make(clause.Set, 0, len(onConflict.DoUpdates))
+	for _, assignment := range onConflict.DoUpdates {
+		matched := false
+		for _, column := range onConflict.Columns {
+			matched = matched || column.Name == assignment.Column.Name
+		}
+		if !matched {
+			doUpdates = append(doUpdates, assignment)
+		}
+	}
+	return doUpdates
+}
+
+// UpdateInsertCreate creates values without MERGE, updating the rows matching the OnConflict
+// columns under UPDLOCK and SERIALIZABLE, then inserting the others with INSERT ... WHERE NOT EXISTS
+func UpdateInsertCreate(db *gorm.DB, onConflict clause.OnConflict, values clause.Values) {
+	if len(onConflict.Columns) == 0 {
+		for _, field := range db.Statement.Schema.PrimaryFields {
+			onConflict.Columns = append(onConflict.Columns, clause.Column{Name: field.DBName})
+		}
+	}
+
+	var where clause.Where
+	for _, column := range onConflict.Columns {
+		where.Exprs = append(where.Exprs, clause.Eq{
+			Column: clause.Column{Table: clause.CurrentTable, Name: column.Name},
+			Value:  clause.Column{Table: "excluded", Name: column.Name},
+		})
+	}
+
+	if doUpdates := conflictUpdates(onConflict); !onConflict.DoNothing && len(doUpdates) > 0 {
+		db.Statement.WriteString("UPDATE ")
+		db.Statement.WriteQuoted(clause.Table{Name: clause.CurrentTable})
+		db.Statement.WriteString(" WITH (UPDLOCK, SERIALIZABLE) SET ")
+		doUpdates.Build(db.Statement)
+		outputInserted(db, conflictKeys(db.Statement, onConflict.Columns))
+		db.Statement.WriteString(" FROM ")
+		writeExcluded(db, values)
+		db.Statement.WriteString(" WHERE ")
+		where.Build(db.Statement)
+		if len(onConflict.Where.Exprs) > 0 {
+			db.Statement.WriteString(" AND (")
+			onConflict.Where.Build(db.Statement)
+			db.Statement.WriteByte(')')
+		}
+		db.Statement.WriteString(";")
+	}
+
+	db.Statement.WriteString("INSERT INTO ")
+	db.Statement.WriteQuoted(clause.Table{Name: clause.CurrentTable})
+	db.Statement.WriteString(" (")
+
+	written := false
+	for _, column := range values.Columns {
+		if db.Statement.Schema.PrioritizedPrimaryField == nil || !db.Statement.Schema.PrioritizedPrimaryField.AutoIncrement || db.Statement.Schema.PrioritizedPrimaryField.DBName != column.Name {
+			if written {
+				db.Statement.WriteByte(',')
+			}
+			written = true
+			db.Statement.WriteQuoted(column.Name)
+		}
+	}
+
+	db.Statement.WriteString(")")
+	outputInserted(db, conflictKeys(db.Statement, onConflict.Columns))
+	db.Statement.WriteString(" SELECT ")
+
+	written = false
+	for _, column := range values.Columns {
+		if db.Statement.Schema.PrioritizedPrimaryField == nil || !db.Statement.Schema.PrioritizedPrimaryField.AutoIncrement || db.Statement.Schema.PrioritizedPrimaryField.DBName != column.Name {
+			if written {
+				db.Statement.WriteByte(',')
+			}
+			written = true
+			db.Statement.WriteQuoted(clause.Column{
+				Table: "excluded",
+				Name:  column.Name,
+			})
+		}
+	}
+
+	db.Statement.WriteString(" FROM ")
+	writeExcluded(db, values)
+	db.Statement.WriteString(" WHERE NOT EXISTS (SELECT 1 FROM ")
+	db.Statement.WriteQuoted(clause.Table{Name: clause.CurrentTable})
+	db.Statement.WriteString(" WITH (UPDLOCK, SERIALIZABLE) WHERE ")
+	where.Build(db.Statement)
+	db.Statement.WriteString(");")
+}
+
+// writeExcluded writes values as the derived table excluded
+func writeExcluded(db *gorm.DB, values clause.Values) {
+	db.Statement.WriteString("(VALUES")
+	for idx, value := range values.Values {
+		if idx > 0 {
+			db.Statement.WriteByte(',')
+		}
+
+		db.Statement.WriteByte('(')
+		db.Statement.AddVar(db.Statement, value...)
+		db.Statement.WriteByte(')')
+	}
+
+	db.Statement.WriteString(") AS excluded (")
+	for idx, column := range values.Columns {
+		if idx > 0 {
+			db.Statement.WriteByte(',')
+		}
+		db.Statement.WriteQuoted(column.Name)
+	}
+	db.Statement.WriteByte(')')
+}
+
+// createReturningFields fields read back through OUTPUT INSERTED when creating
+func createReturningFields(s *schema.Schema) []*schema.Field {
+	if s == nil {
+		return nil
+	}
+
+	fields := s.FieldsWithDefaultDBValue
+	if field := rowVersionField(s); field != nil {
+		for _, f := range fields {
+			if f == field {
+				return fields
+			}
+		}
+		fields = append(fields[:len(fields):len(fields)], field)
+	}
+	return fields
+}
+
+// outputInserted writes the OUTPUT clause of the returning fields, followed by the conflict keys
+// matching the rows to the created elements
+func outputInserted(db *gorm.DB, keys []*schema.Field) {
+	if returning := createReturningFields(db.Statement.Schema); len(returning) > 0 {
 		db.Statement.WriteString(" OUTPUT")
-		for idx, field := range db.Statement.Schema.FieldsWithDefaultDBValue {
+		for idx, field := range append(returning[:len(returning):len(returning)], keys...) {
 			if idx > 0 {
 				db.Statement.WriteString(",")
 			}
@@ -256,3 +712,121 @@ func outputInserted(db *gorm.DB) {
 		}
 	}
 }
+
+// scanByKeys scans the OUTPUT rows of upserts, updated and inserted rows in any order, into the
+// elements with the same conflict keys, and the rows of generated keys into the elements without keys
+func scanByKeys(db *gorm.DB, rows *sql.Rows, returning, keys []*schema.Field, elements []int) (rowsAffected int) {
+	var (
+		fields  = append(returning[:len(returning):len(returning)], keys...)
+		values  = make([]interface{}, len(fields))
+		matched = make([]bool, len(elements))
+	)
+
+	for hasResultSet := true; hasResultSet; hasResultSet = rows.NextResultSet() {
+		for rows.Next() {
+			rowsAffected++
+			for idx, field := range fields {
+				values[idx] = reflect.New(field.FieldType).Interface()
+			}
+
+			if db.AddError(rows.Scan(values...)) != nil {
+				return
+			}
+
+			if idx := matchElement(db, keys, values[len(returning):], elements, matched); idx != -1 {
+				matched[idx] = true
+				obj := db.Statement.ReflectValue.Index(elements[idx])
+				for i, field := range returning {
+					db.AddError(field.Set(obj, reflect.ValueOf(values[i]).Elem().Interface()))
+				}
+			}
+		}
+	}
+	return
+}
+
+// matchElement returns the position of the first unmatched element whose keys equal the scanned
+// keyValues, or else of the first unmatched element with zero keys, generated by the insert
+func matchElement(db *gorm.DB, keys []*schema.Field, keyValues []interface{}, elements []int, matched []bool) int {
+	generated := -1
+	for idx, element := range elements {
+		if matched[idx] {
+			continue
+		}
+
+		obj := db.Statement.ReflectValue.Index(element)
+		same, zero := true, false
+		for i, key := range keys {
+			value, isZero := key.ValueOf(obj)
+			zero = zero || isZero
+			same = same && sameKey(value, reflect.ValueOf(keyValues[i]).Elem().Interface())
+		}
+
+		if same {
+			return idx
+		} else if zero && generated == -1 {
+			generated = idx
+		}
+	}
+	return generated
+}
+
+// sameKey compares strings like the default case-insensitive collations, ignoring trailing spaces
+func sameKey(v1, v2 interface{}) bool {
+	switch s1 := v1.(type) {
+	case string:
+		s2, ok := v2.(string)
+		return ok && strings.EqualFold(strings.TrimRight(s1, " "), strings.TrimRight(s2, " "))
+	case time.Time:
+		t2, ok := v2.(time.Time)
+		return ok && s1.Equal(t2)
+	}
+	return reflect.DeepEqual(v1, v2)
+}
+
+// conflictKeys returns the fields of the conflict columns, nil unless all of them are fields
+func conflictKeys(stmt *gorm.Statement, columns []clause.Column) []*schema.Field {
+	if stmt.Schema == nil || len(columns) == 0 {
+		return nil
+	}
+
+	keys := make([]*schema.Field, 0, len(columns))
+	for _, column := range columns {
+		field := stmt.Schema.LookUpField(column.Name)
+		if field == nil {
+			return nil
+		}
+		keys = append(keys, field)
+	}
+	return keys
+}
+
+// transactional runs fc in a transaction unless the statement already is in one, committing or
+// rolling back depending on db.Error
+func transactional(db *gorm.DB, fc func()) {
+	if committer, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok && committer != nil {
+		fc()
+		return
+	}
+
+	tx := db.Begin()
+	if tx.Error == gorm.ErrInvalidTransaction {
+		fc()
+		return
+	} else if db.AddError(tx.Error) != nil {
+		return
+	}
+
+	connPool := db.Statement.ConnPool
+	db.Statement.ConnPool = tx.Statement.ConnPool
+	defer func() {
+		db.Statement.ConnPool = connPool
+		if db.Error == nil {
+			db.AddError(tx.Commit().Error)
+		} else {
+			tx.Rollback()
+		}
+	}()
+
+	fc()
+}
@@ -0,0 +1,180 @@
+package sqlserver
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type sequentialDocument struct {
+	ID   UUID `gorm:"primaryKey;default:NEWSEQUENTIALID()"`
+	Name string
+}
+
+type identityDocument struct {
+	ID   uint
+	Name string
+}
+
+func dryRun(t *testing.T) *gorm.DB {
+	return openFake(t, Config{}, newFakeConnPool(t)).Session(&gorm.Session{DryRun: true})
+}
+
+func TestCreateSplitsGeneratedPrimaryKeys(t *testing.T) {
+	db := dryRun(t)
+	id, _ := ParseUUID("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	sql := db.Create(&[]sequentialDocument{{Name: "a"}, {ID: id, Name: "b"}, {Name: "c"}}).Statement.SQL.String()
+	if expects := `INSERT INTO "sequential_documents" ("name","id") OUTPUT INSERTED."id" VALUES (@p1,@p2);` +
+		`INSERT INTO "sequential_documents" ("name") OUTPUT INSERTED."id" VALUES (@p3),(@p4);`; sql != expects {
+		t.Errorf("expects rows with and without primary keys to be inserted apart\nexpects: %v\ngot:     %v", expects, sql)
+	}
+
+	sql = db.Create(&[]identityDocument{{Name: "a"}, {ID: 10, Name: "b"}}).Statement.SQL.String()
+	if !strings.Contains(sql, `SET IDENTITY_INSERT "identity_documents" ON;INSERT INTO "identity_documents" ("name","id")`) ||
+		!strings.Contains(sql, `INSERT INTO "identity_documents" ("name") OUTPUT INSERTED."id" VALUES (@p3);`) {
+		t.Errorf("expects explicit identity values to be inserted with IDENTITY_INSERT, got %v", sql)
+	}
+}
+
+func TestCreateIdentityInsertSchemaQualifiedTable(t *testing.T) {
+	db := openFake(t, Config{DefaultSchema: "sales"}, newFakeConnPool(t)).Session(&gorm.Session{DryRun: true})
+
+	sql := db.Create(&[]identityDocument{{ID: 10, Name: "a"}}).Statement.SQL.String()
+	if !strings.HasPrefix(sql, `SET IDENTITY_INSERT "sales"."identity_documents" ON;INSERT INTO "sales"."identity_documents"`) ||
+		!strings.HasSuffix(sql, `SET IDENTITY_INSERT "sales"."identity_documents" OFF;`) {
+		t.Errorf("expects IDENTITY_INSERT of the schema qualified table, got %v", sql)
+	}
+}
+
+func TestCreateRejectsMissingConflictColumns(t *testing.T) {
+	db := dryRun(t)
+
+	err := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "code"}}, UpdateAll: true}).
+		Create(&identityDocument{ID: 1, Name: "a"}).Error
+	if !errors.Is(err, ErrMissingConflictColumns) {
+		t.Errorf("expects ErrMissingConflictColumns, got %v", err)
+	}
+
+	sql := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&identityDocument{ID: 1, Name: "a"}).Statement.SQL.String()
+	if !strings.Contains(sql, `ON "identity_documents"."id" = "excluded"."id"`) {
+		t.Errorf("expects the primary key to match existing rows when no columns are given, got %v", sql)
+	}
+}
+
+type petOwner struct {
+	ID   uint
+	Name string
+	Pets []ownedPet `gorm:"foreignKey:OwnerID"`
+}
+
+type ownedPet struct {
+	ID      uint
+	Name    string
+	OwnerID uint
+}
+
+func TestCreateAssociationsGeneratingPrimaryKeys(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db := openFake(t, Config{}, pool)
+
+	// the identities of the owner and of its pet
+	pool.returns([]string{"id"}, [][]driver.Value{{int64(1)}})
+	pool.returns([]string{"id"}, [][]driver.Value{{int64(2)}})
+
+	owner := petOwner{Name: "jinzhu", Pets: []ownedPet{{Name: "x"}}}
+	if err := db.Create(&owner).Error; err != nil {
+		t.Fatalf("failed to create owner with pets, got error %v", err)
+	}
+
+	if owner.ID != 1 || owner.Pets[0].ID != 2 || owner.Pets[0].OwnerID != 1 {
+		t.Errorf("expects the pet to be created for the owner, got %+v", owner)
+	}
+
+	queries := pool.recorded()
+	if len(queries) != 4 || !strings.HasPrefix(queries[2], `INSERT INTO "owned_pets" ("name","owner_id") OUTPUT INSERTED."id" VALUES`) {
+		t.Errorf("expects the pets generating their primary key to be inserted, got %v", queries)
+	}
+
+	pool.returns([]string{"id"}, [][]driver.Value{{int64(3)}})
+
+	document := identityDocument{Name: "a"}
+	if err := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&document).Error; err != nil {
+		t.Fatalf("failed to upsert, got error %v", err)
+	}
+
+	if queries := pool.recorded(); document.ID != 3 || len(queries) != 3 || !strings.HasPrefix(queries[1], `INSERT INTO "identity_documents"`) {
+		t.Errorf("expects the row generating its primary key to be inserted, got %v and id %v", queries, document.ID)
+	}
+}
+
+func TestMergeCreateSchemaQualifiedTable(t *testing.T) {
+	db := openFake(t, Config{DefaultSchema: "sales"}, newFakeConnPool(t)).Session(&gorm.Session{DryRun: true})
+
+	sql := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&identityDocument{ID: 1, Name: "a"}).Statement.SQL.String()
+	if !strings.HasPrefix(sql, `MERGE INTO "sales"."identity_documents" WITH (HOLDLOCK)`) ||
+		!strings.Contains(sql, `ON "identity_documents"."id" = "excluded"."id"`) {
+		t.Errorf("expects the schema qualified table to be merged into, got %v", sql)
+	}
+}
+
+type upsertDocument struct {
+	ID   uint
+	Code string `gorm:"unique"`
+	Name string
+}
+
+func TestUpdateInsertCreateMatchesOutputByKeys(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db := openFake(t, Config{UpsertStrategy: UpsertUpdateInsert}, pool)
+
+	// the updated row of the existing document b, then the inserted row of the new document a
+	pool.returns([]string{"id", "code"}, [][]driver.Value{{int64(7), "B"}}, [][]driver.Value{{int64(8), "a"}})
+
+	documents := []upsertDocument{{Code: "a", Name: "new"}, {Code: "b", Name: "existing"}}
+	result := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "code"}}, UpdateAll: true}).Create(&documents)
+	if result.Error != nil {
+		t.Fatalf("failed to upsert, got error %v", result.Error)
+	}
+
+	if documents[0].ID != 8 || documents[1].ID != 7 {
+		t.Errorf("expects the rows to be matched by code, got ids %v and %v", documents[0].ID, documents[1].ID)
+	}
+
+	if result.RowsAffected != 2 {
+		t.Errorf("expects 2 rows affected, got %v", result.RowsAffected)
+	}
+
+	// the existing document matched by its primary key, then the one generating it
+	pool.returns([]string{"id", "id"}, [][]driver.Value{{int64(1), int64(1)}}, [][]driver.Value{{int64(9), int64(9)}})
+
+	documents = []upsertDocument{{ID: 1, Code: "c"}, {Code: "d"}}
+	if err := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&documents).Error; err != nil {
+		t.Fatalf("failed to upsert, got error %v", err)
+	}
+
+	if documents[0].ID != 1 || documents[1].ID != 9 {
+		t.Errorf("expects the generated id to be returned to the new document, got ids %v and %v", documents[0].ID, documents[1].ID)
+	}
+}
+
+func TestUpdateInsertCreateSchemaQualifiedTable(t *testing.T) {
+	db := openFake(t, Config{DefaultSchema: "sales", UpsertStrategy: UpsertUpdateInsert}, newFakeConnPool(t)).Session(&gorm.Session{DryRun: true})
+
+	sql := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "code"}}, UpdateAll: true}).
+		Create(&upsertDocument{Code: "a", Name: "new"}).Statement.SQL.String()
+	for _, expects := range []string{
+		`UPDATE "sales"."upsert_documents" WITH (UPDLOCK, SERIALIZABLE) SET`,
+		`WHERE "upsert_documents"."code" = "excluded"."code"`,
+		`INSERT INTO "sales"."upsert_documents" ("code","name")`,
+		`WHERE NOT EXISTS (SELECT 1 FROM "sales"."upsert_documents" WITH (UPDLOCK, SERIALIZABLE)`,
+	} {
+		if !strings.Contains(sql, expects) {
+			t.Errorf("expects the schema qualified table to be upserted, %q not found in %v", expects, sql)
+		}
+	}
+}
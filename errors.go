@@ -0,0 +1,103 @@
+package sqlserver
+
+import (
+	"errors"
+	"strings"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrDuplicatedKey unique or primary key violation, error 2627 / 2601
+	ErrDuplicatedKey = errors.New("duplicated key not allowed")
+	// ErrForeignKeyViolated foreign key constraint violation, error 547
+	ErrForeignKeyViolated = errors.New("violates foreign key constraint")
+	// ErrCheckConstraintViolated check constraint violation, error 547
+	ErrCheckConstraintViolated = errors.New("violates check constraint")
+	// ErrNotNullViolated NULL inserted into a NOT NULL column, error 515
+	ErrNotNullViolated = errors.New("violates not null constraint")
+	// ErrDeadlock transaction was chosen as the deadlock victim, error 1205
+	ErrDeadlock = errors.New("deadlock detected")
+	// ErrLockTimeout lock request time out period exceeded, error 1222
+	ErrLockTimeout = errors.New("lock request timeout")
+	// ErrUncommittableTransaction the transaction is doomed, XACT_STATE() = -1, and can only be rolled
+	// back entirely, errors 3930 / 3931
+	ErrUncommittableTransaction = errors.New("uncommittable transaction, it can only be rolled back entirely")
+	// ErrInvalidSavePoint savepoint name is empty, longer than 32 characters or can't be quoted
+	ErrInvalidSavePoint = errors.New("invalid savepoint name")
+	// ErrMissingConflictColumns the columns of clause.OnConflict aren't created, so can't match the
+	// existing rows
+	ErrMissingConflictColumns = errors.New("conflict columns are not created")
+)
+
+// TranslatedError wraps a mssql.Error matched to one of the sentinel errors of this package,
+// errors.Is reports the sentinel while errors.As still reaches the original mssql.Error
+type TranslatedError struct {
+	Err   error
+	Cause mssql.Error
+}
+
+func (e *TranslatedError) Error() string {
+	return e.Err.Error() + ": " + e.Cause.Error()
+}
+
+func (e *TranslatedError) Is(target error) bool {
+	return e.Err == target
+}
+
+func (e *TranslatedError) Unwrap() error {
+	return e.Cause
+}
+
+// Translate converts the SQL Server error numbers into the typed errors of this package
+func (dialector Dialector) Translate(err error) error {
+	var mssqlErr mssql.Error
+	if err == nil || !errors.As(err, &mssqlErr) {
+		return err
+	}
+
+	var translated *TranslatedError
+	if errors.As(err, &translated) {
+		return err
+	}
+
+	var sentinel error
+	switch mssqlErr.Number {
+	case 2627, 2601:
+		sentinel = ErrDuplicatedKey
+	case 547:
+		if strings.Contains(mssqlErr.Message, "CHECK constraint") {
+			sentinel = ErrCheckConstraintViolated
+		} else {
+			sentinel = ErrForeignKeyViolated
+		}
+	case 515:
+		sentinel = ErrNotNullViolated
+	case 1205:
+		sentinel = ErrDeadlock
+	case 1222:
+		sentinel = ErrLockTimeout
+	case 3930, 3931:
+		sentinel = ErrUncommittableTransaction
+	default:
+		return err
+	}
+
+	return &TranslatedError{Err: sentinel, Cause: mssqlErr}
+}
+
+func (dialector Dialector) translateError(db *gorm.DB) {
+	if db.Error != nil {
+		db.Error = dialector.Translate(db.Error)
+	}
+}
+
+func (dialector Dialector) registerErrorTranslator(db *gorm.DB) {
+	db.Callback().Create().Register("sqlserver:translate_error", dialector.translateError)
+	db.Callback().Query().Register("sqlserver:translate_error", dialector.translateError)
+	db.Callback().Update().Register("sqlserver:translate_error", dialector.translateError)
+	db.Callback().Delete().Register("sqlserver:translate_error", dialector.translateError)
+	db.Callback().Row().Register("sqlserver:translate_error", dialector.translateError)
+	db.Callback().Raw().Register("sqlserver:translate_error", dialector.translateError)
+}
@@ -0,0 +1,93 @@
+package sqlserver
+
+import (
+	"errors"
+	"testing"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	"gorm.io/gorm"
+)
+
+type translatedUser struct {
+	ID   uint
+	Name string
+}
+
+func TestTranslate(t *testing.T) {
+	var dialector Dialector
+
+	tests := []struct {
+		err      mssql.Error
+		expected error
+	}{
+		{mssql.Error{Number: 2627, Message: "Violation of PRIMARY KEY constraint"}, ErrDuplicatedKey},
+		{mssql.Error{Number: 2601, Message: "Cannot insert duplicate key row"}, ErrDuplicatedKey},
+		{mssql.Error{Number: 547, Message: "The INSERT statement conflicted with the FOREIGN KEY constraint"}, ErrForeignKeyViolated},
+		{mssql.Error{Number: 547, Message: "The INSERT statement conflicted with the CHECK constraint"}, ErrCheckConstraintViolated},
+		{mssql.Error{Number: 515, Message: "Cannot insert the value NULL into column"}, ErrNotNullViolated},
+		{mssql.Error{Number: 1205, Message: "Transaction was deadlocked"}, ErrDeadlock},
+		{mssql.Error{Number: 1222, Message: "Lock request time out period exceeded"}, ErrLockTimeout},
+		{mssql.Error{Number: 3930, Message: "The current transaction cannot be committed"}, ErrUncommittableTransaction},
+	}
+
+	for _, test := range tests {
+		err := dialector.Translate(test.err)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("expects error %v to be translated to %v, got %v", test.err.Number, test.expected, err)
+		}
+
+		var mssqlErr mssql.Error
+		if !errors.As(err, &mssqlErr) || mssqlErr.Number != test.err.Number {
+			t.Errorf("expects the translated error %v to wrap the mssql.Error, got %#v", test.err.Number, err)
+		}
+
+		if translated := dialector.Translate(err); translated != err {
+			t.Errorf("expects translated errors to be returned as is, got %v", translated)
+		}
+	}
+
+	unknown := mssql.Error{Number: 208, Message: "Invalid object name"}
+	if err := dialector.Translate(unknown); err != error(unknown) {
+		t.Errorf("expects unknown error numbers to be returned as is, got %v", err)
+	}
+
+	if err := dialector.Translate(gorm.ErrRecordNotFound); err != gorm.ErrRecordNotFound {
+		t.Errorf("expects other errors to be returned as is, got %v", err)
+	}
+}
+
+func TestTranslateErrorCallbacks(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db := openFake(t, Config{}, pool)
+
+	pool.fail(mssql.Error{Number: 2627, Message: "Violation of PRIMARY KEY constraint"})
+	err := db.Create(&translatedUser{Name: "jinzhu"}).Error
+	if !errors.Is(err, ErrDuplicatedKey) {
+		t.Errorf("expects Create to return ErrDuplicatedKey, got %v", err)
+	}
+
+	var mssqlErr mssql.Error
+	if !errors.As(err, &mssqlErr) || mssqlErr.Number != 2627 {
+		t.Errorf("expects the mssql.Error to be reachable with errors.As, got %#v", err)
+	}
+
+	pool.fail(mssql.Error{Number: 547, Message: "The UPDATE statement conflicted with the CHECK constraint"})
+	if err := db.Model(&translatedUser{ID: 1}).Update("name", "").Error; !errors.Is(err, ErrCheckConstraintViolated) {
+		t.Errorf("expects Update to return ErrCheckConstraintViolated, got %v", err)
+	}
+
+	pool.fail(mssql.Error{Number: 1222, Message: "Lock request time out period exceeded"})
+	if err := db.Find(&[]translatedUser{}).Error; !errors.Is(err, ErrLockTimeout) {
+		t.Errorf("expects Find to return ErrLockTimeout, got %v", err)
+	}
+
+	pool.fail(mssql.Error{Number: 547, Message: "The DELETE statement conflicted with the REFERENCE constraint"})
+	if err := db.Delete(&translatedUser{ID: 1}).Error; !errors.Is(err, ErrForeignKeyViolated) {
+		t.Errorf("expects Delete to return ErrForeignKeyViolated, got %v", err)
+	}
+
+	pool.fail(mssql.Error{Number: 1205, Message: "Transaction was deadlocked"})
+	if err := db.Exec("UPDATE translated_users SET name = ?", "jinzhu").Error; !errors.Is(err, ErrDeadlock) {
+		t.Errorf("expects Exec to return ErrDeadlock, got %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeDriver answers the server version query, and returns the result sets scripted on the
+// fakeConnPool of the DSN, or no rows, for any other query
+type fakeDriver struct{}
+
+type fakeDriverConn struct {
+	pool *fakeConnPool
+}
+
+type fakeDriverStmt struct {
+	pool  *fakeConnPool
+	query string
+}
+
+type fakeDriverRows struct {
+	columns []string
+	sets    [][][]driver.Value
+}
+
+// fakeResult result sets returned by a query
+type fakeResult struct {
+	columns []string
+	sets    [][][]driver.Value
+}
+
+var fakePools sync.Map
+
+func init() {
+	sql.Register("sqlserver_fake", fakeDriver{})
+}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	pool, _ := fakePools.Load(dsn)
+	return fakeDriverConn{pool: pool.(*fakeConnPool)}, nil
+}
+
+func (conn fakeDriverConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeDriverStmt{pool: conn.pool, query: query}, nil
+}
+
+func (fakeDriverConn) Close() error                   { return nil }
+func (conn fakeDriverConn) Begin() (driver.Tx, error) { return conn, nil }
+func (fakeDriverConn) Commit() error                  { return nil }
+func (fakeDriverConn) Rollback() error                { return nil }
+
+func (fakeDriverStmt) Close() error  { return nil }
+func (fakeDriverStmt) NumInput() int { return -1 }
+
+func (fakeDriverStmt) Exec([]driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (stmt fakeDriverStmt) Query([]driver.Value) (driver.Rows, error) {
+	if strings.Contains(stmt.query, "SERVERPROPERTY") {
+		return &fakeDriverRows{
+			columns: []string{"version", "edition"},
+			sets:    [][][]driver.Value{{{"15.0.2000.5", "Developer Edition (64-bit)"}}},
+		}, nil
+	}
+
+	result := stmt.pool.nextResult()
+	return &fakeDriverRows{columns: result.columns, sets: result.sets}, nil
+}
+
+func (rows *fakeDriverRows) Columns() []string { return rows.columns }
+func (rows *fakeDriverRows) Close() error      { return nil }
+
+func (rows *fakeDriverRows) Next(dest []driver.Value) error {
+	if len(rows.sets) == 0 || len(rows.sets[0]) == 0 {
+		return io.EOF
+	}
+	copy(dest, rows.sets[0][0])
+	rows.sets[0] = rows.sets[0][1:]
+	return nil
+}
+
+func (rows *fakeDriverRows) HasNextResultSet() bool {
+	return len(rows.sets) > 1
+}
+
+func (rows *fakeDriverRows) NextResultSet() error {
+	if len(rows.sets) <= 1 {
+		return io.EOF
+	}
+	rows.sets = rows.sets[1:]
+	return nil
+}
+
+// fakeStatement statement received by a fakeConnPool, with its arguments
+type fakeStatement struct {
+	query string
+	args  []interface{}
+}
+
+// fakeConnPool records the statements it receives and fails them with the scripted errors, in order
+type fakeConnPool struct {
+	db *sql.DB
+
+	mu         sync.Mutex
+	errs       []error
+	results    []fakeResult
+	statements []fakeStatement
+}
+
+func newFakeConnPool(t *testing.T) *fakeConnPool {
+	pool := &fakeConnPool{}
+	dsn := fmt.Sprintf("%s/%p", t.Name(), pool)
+	fakePools.Store(dsn, pool)
+
+	db, err := sql.Open("sqlserver_fake", dsn)
+	if err != nil {
+		t.Fatalf("failed to open fake driver, got error %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		fakePools.Delete(dsn)
+	})
+	pool.db = db
+	return pool
+}
+
+// returns scripts the result sets of the next query
+func (pool *fakeConnPool) returns(columns []string, sets ...[][]driver.Value) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.results = append(pool.results, fakeResult{columns: columns, sets: sets})
+}
+
+func (pool *fakeConnPool) nextResult() (result fakeResult) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if len(pool.results) > 0 {
+		result = pool.results[0]
+		pool.results = pool.results[1:]
+	}
+	return
+}
+
+// fail scripts errs to be returned by the next statements
+func (pool *fakeConnPool) fail(errs ...error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.errs = append(pool.errs, errs...)
+}
+
+// record records query and its args, returning the next scripted error if any
+func (pool *fakeConnPool) record(query string, args ...interface{}) error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.statements = append(pool.statements, fakeStatement{query: query, args: args})
+	if len(pool.errs) > 0 {
+		err := pool.errs[0]
+		pool.errs = pool.errs[1:]
+		return err
+	}
+	return nil
+}
+
+// recorded returns the queries of the recorded statements, clearing them
+func (pool *fakeConnPool) recorded() []string {
+	var queries []string
+	for _, stmt := range pool.recordedStatements() {
+		queries = append(queries, stmt.query)
+	}
+	return queries
+}
+
+// recordedStatements returns the recorded statements, clearing them
+func (pool *fakeConnPool) recordedStatements() []fakeStatement {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	statements := pool.statements
+	pool.statements = nil
+	return statements
+}
+
+func (pool *fakeConnPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if err := pool.record(query); err != nil {
+		return nil, err
+	}
+	return pool.db.PrepareContext(ctx, query)
+}
+
+func (pool *fakeConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := pool.record(query, args...); err != nil {
+		return nil, err
+	}
+	return pool.db.ExecContext(ctx, query, args...)
+}
+
+func (pool *fakeConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if err := pool.record(query, args...); err != nil {
+		return nil, err
+	}
+	return pool.db.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext can't fail with the scripted errors, *sql.Row reports them when scanning
+func (pool *fakeConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	pool.record(query, args...)
+	return pool.db.QueryRowContext(ctx, query, args...)
+}
+
+func (pool *fakeConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	if err := pool.record("BEGIN TRANSACTION"); err != nil {
+		return nil, err
+	}
+	return &fakeTx{ConnPool: pool, pool: pool}, nil
+}
+
+// fakeTx transaction of a fakeConnPool, recording its statements on the pool, which can't begin
+// nested transactions like *sql.Tx
+type fakeTx struct {
+	gorm.ConnPool
+	pool *fakeConnPool
+}
+
+func (tx *fakeTx) Commit() error {
+	return tx.pool.record("COMMIT TRANSACTION")
+}
+
+func (tx *fakeTx) Rollback() error {
+	return tx.pool.record("ROLLBACK TRANSACTION")
+}
+
+// openFake opens a gorm.DB on pool, clearing the statements recorded while initializing
+func openFake(t *testing.T, config Config, pool *fakeConnPool) *gorm.DB {
+	config.Conn = pool
+	db, err := gorm.Open(New(config), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to open db, got error %v", err)
+	}
+	pool.recorded()
+	return db
+}
@@ -0,0 +1,246 @@
+package sqlserver
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TableHints table hints written after the table of SELECT, UPDATE and DELETE statements, or after
+// the joined table or alias Table
+//
+//	db.Clauses(sqlserver.TableHint("UPDLOCK", "ROWLOCK", "READPAST")).First(&job)
+//	db.Joins("Company").Clauses(sqlserver.TableHint("NOLOCK").For("Company")).Find(&users)
+type TableHints struct {
+	Table string
+	Hints []string
+}
+
+// TableHint table hints of the statement table, such as NOLOCK, UPDLOCK or INDEX(idx_name)
+func TableHint(hints ...string) TableHints {
+	return TableHints{Hints: hints}
+}
+
+// For applies the hints to the joined table or alias table
+func (hints TableHints) For(table string) TableHints {
+	hints.Table = table
+	return hints
+}
+
+func (TableHints) Name() string {
+	return "TABLE HINTS"
+}
+
+func (hints TableHints) Build(builder clause.Builder) {
+	if len(hints.Hints) > 0 {
+		builder.WriteString("WITH (")
+		builder.WriteString(strings.Join(hints.Hints, ", "))
+		builder.WriteByte(')')
+	}
+}
+
+func (hints TableHints) MergeClause(c *clause.Clause) {
+	all, _ := c.Expression.(tableHintsList)
+	c.Expression = append(all[:len(all):len(all)], hints)
+}
+
+// tableHintsList table hints of the tables of a statement, built by the FROM and UPDATE clause builders
+type tableHintsList []TableHints
+
+func (tableHintsList) Build(clause.Builder) {}
+
+// QueryOptions query hints written in the OPTION clause at the end of the statement
+//
+//	db.Clauses(sqlserver.QueryOption("RECOMPILE", "MAXDOP 1")).Find(&users)
+type QueryOptions struct {
+	Options []string
+}
+
+// QueryOption query hints, such as RECOMPILE, MAXDOP 1 or OPTIMIZE FOR UNKNOWN
+func QueryOption(options ...string) QueryOptions {
+	return QueryOptions{Options: options}
+}
+
+func (QueryOptions) Name() string {
+	return "OPTION"
+}
+
+func (options QueryOptions) Build(builder clause.Builder) {
+	builder.WriteByte('(')
+	builder.WriteString(strings.Join(options.Options, ", "))
+	builder.WriteByte(')')
+}
+
+func (options QueryOptions) MergeClause(c *clause.Clause) {
+	if v, ok := c.Expression.(QueryOptions); ok {
+		options.Options = append(v.Options[:len(v.Options):len(v.Options)], options.Options...)
+	}
+	c.Expression = options
+}
+
+// tableHintsOf returns the table hints of table, main is whether table is the statement table
+func tableHintsOf(stmt *gorm.Statement, table clause.Table, main bool) (hints []string) {
+	if c, ok := stmt.Clauses["FOR"]; ok {
+		if locking, ok := c.Expression.(clause.Locking); ok && tableMatches(stmt, table, main, locking.Table.Name) {
+			hints = append(hints, lockingHints(locking)...)
+		}
+	}
+
+	if c, ok := stmt.Clauses["TABLE HINTS"]; ok {
+		all, _ := c.Expression.(tableHintsList)
+		for _, tableHints := range all {
+			if tableMatches(stmt, table, main, tableHints.Table) {
+				hints = append(hints, tableHints.Hints...)
+			}
+		}
+	}
+	return
+}
+
+// tableMatches returns whether the target of a clause, the statement table when empty, is table
+func tableMatches(stmt *gorm.Statement, table clause.Table, main bool, target string) bool {
+	if target == "" || target == clause.CurrentTable {
+		return main
+	}
+
+	name := table.Name
+	if name == clause.CurrentTable {
+		name = stmt.Table
+	}
+	return target == name || target == table.Alias
+}
+
+// lockingHints translates clause.Locking to table hints, UPDATE to UPDLOCK, ROWLOCK and SHARE to
+// HOLDLOCK, the NOWAIT and SKIP LOCKED options to NOWAIT and READPAST
+func lockingHints(locking clause.Locking) (hints []string) {
+	switch strength := strings.ToUpper(locking.Strength); {
+	case strings.Contains(strength, "UPDATE"):
+		hints = append(hints, "UPDLOCK", "ROWLOCK")
+	case strings.Contains(strength, "SHARE"):
+		hints = append(hints, "HOLDLOCK")
+	}
+
+	switch strings.ToUpper(locking.Options) {
+	case "NOWAIT":
+		hints = append(hints, "NOWAIT")
+	case "SKIP LOCKED":
+		hints = append(hints, "READPAST")
+	}
+	return
+}
+
+func hasTableHints(stmt *gorm.Statement) bool {
+	_, hasHints := stmt.Clauses["TABLE HINTS"]
+	_, hasLocking := stmt.Clauses["FOR"]
+	return hasHints || hasLocking
+}
+
+// writeTableHints writes the table hints of table, if any
+func writeTableHints(stmt *gorm.Statement, table clause.Table, main bool) {
+	if hints := tableHintsOf(stmt, table, main); len(hints) > 0 {
+		stmt.WriteByte(' ')
+		TableHints{Hints: hints}.Build(stmt)
+	}
+}
+
+// writeTable writes table with its period and table hints, the period goes before the alias
+func writeTable(stmt *gorm.Statement, table clause.Table, main bool) {
+	alias := table.Alias
+	table.Alias = ""
+	stmt.WriteQuoted(table)
+	writeSystemTime(stmt, clause.Table{Name: table.Name, Alias: alias}, main)
+
+	if alias != "" {
+		stmt.WriteByte(' ')
+		stmt.WriteQuoted(alias)
+	}
+	writeTableHints(stmt, clause.Table{Name: table.Name, Alias: alias}, main)
+}
+
+// buildFrom builds the tables and the joins of from with their periods and table hints
+func buildFrom(stmt *gorm.Statement, from clause.From) {
+	if len(from.Tables) > 0 {
+		for idx, table := range from.Tables {
+			if idx > 0 {
+				stmt.WriteByte(',')
+			}
+
+			writeTable(stmt, table, idx == 0)
+		}
+	} else {
+		writeTable(stmt, clause.Table{Name: clause.CurrentTable}, true)
+	}
+
+	for _, join := range from.Joins {
+		stmt.WriteByte(' ')
+		if join.Expression != nil {
+			join.Expression.Build(stmt)
+			continue
+		}
+
+		if join.Type != "" {
+			stmt.WriteString(string(join.Type))
+			stmt.WriteByte(' ')
+		}
+
+		stmt.WriteString("JOIN ")
+		writeTable(stmt, join.Table, false)
+
+		if len(join.ON.Exprs) > 0 {
+			stmt.WriteString(" ON ")
+			join.ON.Build(stmt)
+		} else if len(join.Using) > 0 {
+			stmt.WriteString(" USING (")
+			for idx, c := range join.Using {
+				if idx > 0 {
+					stmt.WriteByte(',')
+				}
+				stmt.WriteQuoted(c)
+			}
+			stmt.WriteByte(')')
+		}
+	}
+}
+
+// fromClauseBuilder builds the FROM clause with the periods and the table hints of the tables
+func fromClauseBuilder(c clause.Clause, builder clause.Builder) {
+	stmt, ok := builder.(*gorm.Statement)
+	from, isFrom := c.Expression.(clause.From)
+	if !ok || !isFrom || (!hasTableHints(stmt) && !hasSystemTime(stmt)) {
+		c.Builder = nil
+		c.Build(builder)
+		return
+	}
+
+	stmt.WriteString("FROM ")
+	buildFrom(stmt, from)
+}
+
+// updateClauseBuilder builds the UPDATE clause with the table hints of the statement table
+func updateClauseBuilder(c clause.Clause, builder clause.Builder) {
+	stmt, ok := builder.(*gorm.Statement)
+	update, isUpdate := c.Expression.(clause.Update)
+	if !ok || !isUpdate || !hasTableHints(stmt) {
+		c.Builder = nil
+		c.Build(builder)
+		return
+	}
+
+	stmt.WriteString("UPDATE ")
+	update.Build(stmt)
+	table := update.Table
+	if table.Name == "" {
+		table = clause.Table{Name: clause.CurrentTable}
+	}
+	writeTableHints(stmt, table, true)
+}
+
+// forClauseBuilder locking is written as table hints by the FROM clause builder, SQL Server doesn't
+// support FOR UPDATE
+func forClauseBuilder(c clause.Clause, builder clause.Builder) {
+	if _, ok := c.Expression.(clause.Locking); !ok {
+		c.Builder = nil
+		c.Build(builder)
+	}
+}
@@ -0,0 +1,142 @@
+package sqlserver
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type hintedCompany struct {
+	ID   uint
+	Name string
+}
+
+type hintedUser struct {
+	ID        uint
+	Name      string
+	CompanyID uint
+	Company   hintedCompany
+}
+
+// trimmedStatements returns the recorded statements without the separator of the clauses writing
+// nothing at their end, such as the FOR clause written as table hints
+func trimmedStatements(pool *fakeConnPool) (statements []string) {
+	for _, statement := range pool.recorded() {
+		statements = append(statements, strings.TrimRight(statement, " "))
+	}
+	return
+}
+
+// expectStatement checks the statement executed by query, apart from the transaction ones
+func expectStatement(t *testing.T, pool *fakeConnPool, query func(), expects string) {
+	t.Helper()
+	query()
+
+	var statements []string
+	for _, statement := range trimmedStatements(pool) {
+		if statement != "BEGIN TRANSACTION" && statement != "COMMIT TRANSACTION" {
+			statements = append(statements, statement)
+		}
+	}
+
+	if len(statements) != 1 || statements[0] != expects {
+		t.Errorf("expects %v, got %v", expects, statements)
+	}
+}
+
+func TestTableHints(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db := openFake(t, Config{}, pool)
+
+	pool.returns([]string{"id", "name"}, [][]driver.Value{{int64(1), "jinzhu"}})
+
+	var user hintedUser
+	if err := db.Clauses(TableHint("NOLOCK")).First(&user).Error; err != nil || user.ID != 1 {
+		t.Fatalf("failed to query with table hints, got user %+v and error %v", user, err)
+	}
+
+	if queries := pool.recorded(); len(queries) != 1 || queries[0] != `SELECT * FROM "hinted_users" WITH (NOLOCK) ORDER BY "hinted_users"."id" OFFSET 0 ROW FETCH NEXT 1 ROWS ONLY` {
+		t.Errorf("expects the hints after the table, got %v", queries)
+	}
+
+	var users []hintedUser
+	expectStatement(t, pool, func() {
+		db.Clauses(TableHint("UPDLOCK", "READPAST"), TableHint("ROWLOCK")).Where("name = ?", "jinzhu").Find(&users)
+	}, `SELECT * FROM "hinted_users" WITH (UPDLOCK, READPAST, ROWLOCK) WHERE name = @p1`)
+
+	expectStatement(t, pool, func() {
+		db.Table("hinted_users AS u").Clauses(TableHint("NOLOCK").For("u")).Find(&users)
+	}, `SELECT * FROM hinted_users AS u WITH (NOLOCK)`)
+
+	expectStatement(t, pool, func() {
+		db.Joins("Company").Clauses(TableHint("NOLOCK"), TableHint("NOLOCK").For("Company")).Find(&users)
+	}, `SELECT "hinted_users"."id","hinted_users"."name","hinted_users"."company_id","Company"."id" AS "Company__id","Company"."name" AS "Company__name" `+
+		`FROM "hinted_users" WITH (NOLOCK) LEFT JOIN "hinted_companies" "Company" WITH (NOLOCK) ON "hinted_users"."company_id" = "Company"."id"`)
+
+	expectStatement(t, pool, func() {
+		db.Clauses(QueryOption("RECOMPILE"), QueryOption("MAXDOP 1")).Find(&users)
+	}, `SELECT * FROM "hinted_users" OPTION (RECOMPILE, MAXDOP 1)`)
+
+	expectStatement(t, pool, func() {
+		db.Model(&hintedUser{ID: 1}).Clauses(TableHint("ROWLOCK"), QueryOption("MAXDOP 1")).Update("name", "hinted")
+	}, `UPDATE "hinted_users" WITH (ROWLOCK) SET "name"=@p1 WHERE "id" = @p2 OPTION (MAXDOP 1)`)
+
+	expectStatement(t, pool, func() {
+		db.Clauses(TableHint("ROWLOCK"), QueryOption("RECOMPILE")).Delete(&hintedUser{ID: 1})
+	}, `DELETE FROM "hinted_users" WITH (ROWLOCK) WHERE "hinted_users"."id" = @p1 OPTION (RECOMPILE)`)
+}
+
+func TestLockingHints(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db := openFake(t, Config{}, pool)
+
+	var users []hintedUser
+	tests := []struct {
+		locking clause.Locking
+		expects string
+	}{
+		{clause.Locking{Strength: "UPDATE"}, `SELECT * FROM "hinted_users" WITH (UPDLOCK, ROWLOCK)`},
+		{clause.Locking{Strength: "SHARE"}, `SELECT * FROM "hinted_users" WITH (HOLDLOCK)`},
+		{clause.Locking{Strength: "UPDATE", Options: "NOWAIT"}, `SELECT * FROM "hinted_users" WITH (UPDLOCK, ROWLOCK, NOWAIT)`},
+		{clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}, `SELECT * FROM "hinted_users" WITH (UPDLOCK, ROWLOCK, READPAST)`},
+	}
+
+	for _, test := range tests {
+		expectStatement(t, pool, func() { db.Clauses(test.locking).Find(&users) }, test.expects)
+	}
+
+	expectStatement(t, pool, func() {
+		db.Clauses(clause.Locking{Strength: "UPDATE"}, TableHint("READPAST")).Where("name = ?", "jinzhu").Find(&users)
+	}, `SELECT * FROM "hinted_users" WITH (UPDLOCK, ROWLOCK, READPAST) WHERE name = @p1`)
+
+	expectStatement(t, pool, func() {
+		db.Joins("Company").Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "Company"}}).Find(&users)
+	}, `SELECT "hinted_users"."id","hinted_users"."name","hinted_users"."company_id","Company"."id" AS "Company__id","Company"."name" AS "Company__name" `+
+		`FROM "hinted_users" LEFT JOIN "hinted_companies" "Company" WITH (UPDLOCK, ROWLOCK) ON "hinted_users"."company_id" = "Company"."id"`)
+
+	pool.returns([]string{"id", "name"}, [][]driver.Value{{int64(1), "jinzhu"}})
+
+	var user hintedUser
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, 1).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Update("name", "locked").Error
+	}); err != nil {
+		t.Fatalf("failed to lock the user, got error %v", err)
+	}
+
+	expects := []string{
+		"BEGIN TRANSACTION",
+		`SELECT * FROM "hinted_users" WITH (UPDLOCK, ROWLOCK) WHERE "hinted_users"."id" = @p1 ORDER BY "hinted_users"."id" OFFSET 0 ROW FETCH NEXT 1 ROWS ONLY`,
+		`UPDATE "hinted_users" SET "name"=@p1 WHERE "id" = @p2`,
+		"COMMIT TRANSACTION",
+	}
+	if queries := trimmedStatements(pool); !reflect.DeepEqual(queries, expects) {
+		t.Errorf("expects the locked user to be updated in the transaction\nexpects: %v\ngot:     %v", expects, queries)
+	}
+}
@@ -0,0 +1,42 @@
+package sqlserver
+
+import (
+	"database/sql"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SnapshotTx transaction options of SNAPSHOT isolation, which requires ALLOW_SNAPSHOT_ISOLATION to
+// be enabled on the database, see Migrator.EnableSnapshotIsolation
+//
+//	db.Transaction(func(tx *gorm.DB) error { ... }, sqlserver.SnapshotTx())
+func SnapshotTx() *sql.TxOptions {
+	return &sql.TxOptions{Isolation: sql.LevelSnapshot}
+}
+
+// isolationLevels isolation levels supported by SQL Server
+var isolationLevels = map[sql.IsolationLevel]string{
+	sql.LevelReadUncommitted: "READ UNCOMMITTED",
+	sql.LevelReadCommitted:   "READ COMMITTED",
+	sql.LevelRepeatableRead:  "REPEATABLE READ",
+	sql.LevelSnapshot:        "SNAPSHOT",
+	sql.LevelSerializable:    "SERIALIZABLE",
+}
+
+// SetIsolationLevel runs SET TRANSACTION ISOLATION LEVEL, applying level to the current transaction
+// of db. Outside of a transaction it returns gorm.ErrInvalidTransaction, as the session of the pooled
+// connection is reset when reused, pass the level to db.Transaction or db.Begin instead
+//
+//	db.Transaction(func(tx *gorm.DB) error { ... }, &sql.TxOptions{Isolation: sql.LevelSerializable})
+func SetIsolationLevel(db *gorm.DB, level sql.IsolationLevel) error {
+	name, ok := isolationLevels[level]
+	if !ok {
+		return fmt.Errorf("isolation level %s is not supported by SQL Server", level)
+	}
+
+	if committer, ok := db.Statement.ConnPool.(gorm.TxCommitter); !ok || committer == nil {
+		return gorm.ErrInvalidTransaction
+	}
+	return db.Exec("SET TRANSACTION ISOLATION LEVEL " + name).Error
+}
@@ -0,0 +1,37 @@
+package sqlserver
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestSetIsolationLevel(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db := openFake(t, Config{}, pool)
+
+	if err := SetIsolationLevel(db, sql.LevelSerializable); !errors.Is(err, gorm.ErrInvalidTransaction) {
+		t.Errorf("expects gorm.ErrInvalidTransaction outside of a transaction, got %v", err)
+	}
+
+	if queries := pool.recorded(); len(queries) != 0 {
+		t.Errorf("expects no isolation level to be set on a pooled connection, got %v", queries)
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := SetIsolationLevel(tx, sql.LevelLinearizable); err == nil {
+			t.Errorf("expects unsupported isolation levels to be rejected")
+		}
+		return SetIsolationLevel(tx, sql.LevelSnapshot)
+	}); err != nil {
+		t.Fatalf("failed to set the isolation level, got error %v", err)
+	}
+
+	expects := []string{"BEGIN TRANSACTION", "SET TRANSACTION ISOLATION LEVEL SNAPSHOT", "COMMIT TRANSACTION"}
+	if queries := pool.recorded(); !reflect.DeepEqual(queries, expects) {
+		t.Errorf("expects %v, got %v", expects, queries)
+	}
+}
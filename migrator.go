@@ -1,48 +1,201 @@
 package sqlserver
 
 import (
+	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
 
+	mssql "github.com/denisenkom/go-mssqldb"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
 )
 
 type Migrator struct {
 	migrator.Migrator
 }
 
+// splitTable returns the schema and the name of table, the schema is SCHEMA_NAME() when not specified.
+// Tables are used as named, like the statements do, Config.DefaultSchema qualifies the table names of
+// the naming strategy only, not the ones of the Tabler models
+func (m Migrator) splitTable(table string) (schema interface{}, name string) {
+	if idx := strings.LastIndex(table, "."); idx != -1 {
+		return table[:idx], table[idx+1:]
+	}
+	return clause.Expr{SQL: "SCHEMA_NAME()"}, table
+}
+
+// currentTable returns the table of stmt qualified by its schema, Statement.Parse and DB.Table only
+// keep the schema in the quoted TableExpr, Table is the name of the table
+func (m Migrator) currentTable(stmt *gorm.Statement) string {
+	if stmt.TableExpr != nil && len(stmt.TableExpr.Vars) == 0 {
+		if table := strings.ReplaceAll(strings.Trim(stmt.TableExpr.SQL, `"`), `"."`, "."); stmt.Quote(table) == stmt.TableExpr.SQL {
+			return table
+		}
+	}
+	return stmt.Table
+}
+
 func (m Migrator) HasTable(value interface{}) bool {
 	var count int
 	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		schema, table := m.splitTable(m.currentTable(stmt))
 		return m.DB.Raw(
-			"SELECT count(*) FROM INFORMATION_SCHEMA.tables WHERE table_name = ? AND table_catalog = ?",
-			stmt.Table, m.CurrentDatabase(),
+			"SELECT count(*) FROM INFORMATION_SCHEMA.tables WHERE table_name = ? AND table_schema = ? AND table_catalog = ?",
+			table, schema, m.CurrentDatabase(),
 		).Row().Scan(&count)
 	})
 	return count > 0
 }
 
+func (m Migrator) CreateTable(values ...interface{}) error {
+	for _, value := range values {
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			schema, _ := m.splitTable(m.currentTable(stmt))
+			if schema, ok := schema.(string); ok {
+				return m.createSchemaIfNotExists(schema)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := m.Migrator.CreateTable(values...); err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			history, ok, err := m.historyTableOf(stmt)
+			if err == nil && ok {
+				err = m.addSystemVersioning(value, stmt, history)
+			}
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// historyTableOf returns the schema qualified history table of the model of stmt implementing
+// TemporalTable
+func (m Migrator) historyTableOf(stmt *gorm.Statement) (string, bool, error) {
+	temporal, ok := temporalTableOf(stmt.Schema)
+	if !ok {
+		return "", false, nil
+	}
+
+	history := temporal.HistoryTable()
+	if history == "" {
+		history = m.currentTable(stmt) + "_history"
+	}
+
+	// HISTORY_TABLE requires a schema, the one of the table by default
+	if !strings.Contains(history, ".") {
+		schema, _ := m.splitTable(m.currentTable(stmt))
+		name, ok := schema.(string)
+		if !ok {
+			if err := m.DB.Raw("SELECT SCHEMA_NAME()").Row().Scan(&name); err != nil {
+				return "", false, err
+			}
+		}
+		history = name + "." + history
+	}
+	return history, true, nil
+}
+
+// addSystemVersioning replaces the period columns of the model by generated ones and turns on the
+// system versioning of its table
+func (m Migrator) addSystemVersioning(value interface{}, stmt *gorm.Statement, history string) error {
+	return m.DB.Transaction(func(tx *gorm.DB) error {
+		txMigrator := m
+		txMigrator.DB = tx
+
+		start, end := periodColumns(m.DB.NamingStrategy)
+		hidden := map[string]string{}
+		for _, name := range []string{start, end} {
+			if field := stmt.Schema.LookUpField(name); field != nil && field.DBName != "" {
+				if err := txMigrator.DropColumn(value, field.DBName); err != nil {
+					return err
+				}
+			} else {
+				hidden[name] = " HIDDEN"
+			}
+		}
+
+		name := m.currentTable(stmt)
+		table := clause.Table{Name: name}
+		if err := tx.Exec(
+			"ALTER TABLE ? ADD ? datetime2 GENERATED ALWAYS AS ROW START"+hidden[start]+" NOT NULL CONSTRAINT ? DEFAULT SYSUTCDATETIME(), "+
+				"? datetime2 GENERATED ALWAYS AS ROW END"+hidden[end]+" NOT NULL CONSTRAINT ? DEFAULT CONVERT(datetime2, '9999-12-31 23:59:59.9999999'), "+
+				"PERIOD FOR SYSTEM_TIME (?, ?)",
+			table,
+			clause.Column{Name: start}, clause.Column{Name: m.defaultConstraintName(name, start)},
+			clause.Column{Name: end}, clause.Column{Name: m.defaultConstraintName(name, end)},
+			clause.Column{Name: start}, clause.Column{Name: end},
+		).Error; err != nil {
+			return err
+		}
+
+		return tx.Exec("ALTER TABLE ? SET (SYSTEM_VERSIONING = ON (HISTORY_TABLE = ?))", table, clause.Table{Name: history}).Error
+	})
+}
+
+func (m Migrator) createSchemaIfNotExists(schema string) error {
+	var count int
+	if err := m.DB.Raw("SELECT count(*) FROM sys.schemas WHERE name = ?", schema).Row().Scan(&count); err != nil || count > 0 {
+		return err
+	}
+
+	return m.DB.Exec("CREATE SCHEMA ?", clause.Table{Name: schema}).Error
+}
+
 func (m Migrator) DropTable(values ...interface{}) error {
 	values = m.ReorderModels(values, false)
 	for i := len(values) - 1; i >= 0; i-- {
 		tx := m.DB.Session(&gorm.Session{})
 		if err := m.RunWithValue(values[i], func(stmt *gorm.Statement) error {
+			table := m.currentTable(stmt)
+
 			type constraint struct {
 				Name   string
 				Parent string
 			}
 			var constraints []constraint
-			err := tx.Raw("SELECT name, OBJECT_NAME(parent_object_id) as parent FROM sys.foreign_keys WHERE referenced_object_id = object_id(?)", stmt.Table).Scan(&constraints).Error
+			err := tx.Raw("SELECT name, OBJECT_SCHEMA_NAME(parent_object_id) + '.' + OBJECT_NAME(parent_object_id) as parent FROM sys.foreign_keys WHERE referenced_object_id = object_id(?)", table).Scan(&constraints).Error
 
 			for _, c := range constraints {
 				if err == nil {
-					err = tx.Exec("ALTER TABLE ? DROP CONSTRAINT ?;", gorm.Expr(c.Parent), gorm.Expr(c.Name)).Error
+					err = tx.Exec("ALTER TABLE ? DROP CONSTRAINT ?;", clause.Table{Name: c.Parent}, clause.Column{Name: c.Name}).Error
 				}
 			}
 
+			// system-versioned tables can't be dropped, drop their history table as well
+			var (
+				history  string
+				temporal bool
+			)
 			if err == nil {
-				err = tx.Exec("DROP TABLE IF EXISTS ?", clause.Table{Name: stmt.Table}).Error
+				history, temporal, err = m.historyTableOf(stmt)
+			}
+
+			if err == nil && temporal {
+				var temporalType sql.NullInt64
+				if err = tx.Raw("SELECT OBJECTPROPERTY(OBJECT_ID(?), 'TableTemporalType')", table).Row().Scan(&temporalType); err == nil && temporalType.Int64 == 2 {
+					err = tx.Exec("ALTER TABLE ? SET (SYSTEM_VERSIONING = OFF)", clause.Table{Name: table}).Error
+				}
+			}
+
+			if err == nil {
+				err = tx.Exec("DROP TABLE IF EXISTS ?", clause.Table{Name: table}).Error
+			}
+
+			if err == nil && temporal {
+				err = tx.Exec("DROP TABLE IF EXISTS ?", clause.Table{Name: history}).Error
 			}
 
 			return err
@@ -60,7 +213,7 @@ func (m Migrator) RenameTable(oldName, newName interface{}) error {
 	} else {
 		stmt := &gorm.Statement{DB: m.DB}
 		if err := stmt.Parse(oldName); err == nil {
-			oldTable = stmt.Table
+			oldTable = m.currentTable(stmt)
 		} else {
 			return err
 		}
@@ -71,15 +224,31 @@ func (m Migrator) RenameTable(oldName, newName interface{}) error {
 	} else {
 		stmt := &gorm.Statement{DB: m.DB}
 		if err := stmt.Parse(newName); err == nil {
-			newTable = stmt.Table
+			newTable = m.currentTable(stmt)
 		} else {
 			return err
 		}
 	}
 
+	oldSchema, oldTableName := m.splitTable(oldTable)
+	newSchema, newTableName := m.splitTable(newTable)
+	if newSchema, ok := newSchema.(string); ok && newSchema != oldSchema {
+		// sp_rename can't move objects between schemas
+		if err := m.DB.Exec(
+			"ALTER SCHEMA ? TRANSFER ?", clause.Table{Name: newSchema}, clause.Table{Name: oldTable},
+		).Error; err != nil {
+			return err
+		}
+		oldTable = newSchema + "." + oldTableName
+	}
+
+	if oldTableName == newTableName {
+		return nil
+	}
+
 	return m.DB.Exec(
 		"sp_rename @objname = ?, @newname = ?;",
-		clause.Table{Name: oldTable}, clause.Table{Name: newTable},
+		oldTable, newTableName,
 	).Error
 }
 
@@ -92,32 +261,433 @@ func (m Migrator) HasColumn(value interface{}, field string) bool {
 			name = field.DBName
 		}
 
+		schema, table := m.splitTable(m.currentTable(stmt))
 		return m.DB.Raw(
-			"SELECT count(*) FROM INFORMATION_SCHEMA.columns WHERE table_catalog = ? AND table_name = ? AND column_name = ?",
-			currentDatabase, stmt.Table, name,
+			"SELECT count(*) FROM INFORMATION_SCHEMA.columns WHERE table_catalog = ? AND table_schema = ? AND table_name = ? AND column_name = ?",
+			currentDatabase, schema, table, name,
 		).Row().Scan(&count)
 	})
 
 	return count > 0
 }
 
+func (m Migrator) FullDataTypeOf(field *schema.Field) (expr clause.Expr) {
+	expr.SQL = m.DataTypeOf(field)
+
+	if field.NotNull {
+		expr.SQL += " NOT NULL"
+	}
+
+	if field.Unique {
+		expr.SQL += " UNIQUE"
+	}
+
+	if defaultValue, ok := m.defaultValueOf(field); ok {
+		// name the default constraint, so it can be dropped without looking up the generated name
+		if field.Schema != nil {
+			expr.SQL += " CONSTRAINT " + m.DB.Statement.Quote(m.defaultConstraintName(field.Schema.Table, field.DBName))
+		}
+		expr.SQL += " DEFAULT " + defaultValue
+	}
+
+	return
+}
+
+func (m Migrator) defaultValueOf(field *schema.Field) (string, bool) {
+	if field.HasDefaultValue && (field.DefaultValueInterface != nil || field.DefaultValue != "") {
+		if field.DefaultValueInterface != nil {
+			defaultStmt := &gorm.Statement{Vars: []interface{}{field.DefaultValueInterface}}
+			m.Dialector.BindVarTo(defaultStmt, defaultStmt, field.DefaultValueInterface)
+			return m.Dialector.Explain(defaultStmt.SQL.String(), field.DefaultValueInterface), true
+		} else if field.DefaultValue != "(-)" {
+			return field.DefaultValue, true
+		}
+	}
+	return "", false
+}
+
+func (m Migrator) defaultConstraintName(table, column string) string {
+	_, table = m.splitTable(table)
+	return fmt.Sprintf("DF_%s_%s", table, column)
+}
+
+func (m Migrator) DropColumn(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if field := stmt.Schema.LookUpField(name); field != nil {
+			name = field.DBName
+		}
+
+		return m.DB.Transaction(func(tx *gorm.DB) error {
+			table := clause.Table{Name: m.currentTable(stmt)}
+			dependencies, err := m.columnDependencies(tx, table.Name, name)
+			if err == nil {
+				err = m.dropColumnDependencies(tx, table, dependencies)
+			}
+
+			if err == nil {
+				err = tx.Exec("ALTER TABLE ? DROP COLUMN ?", table, clause.Column{Name: name}).Error
+			}
+			return err
+		})
+	})
+}
+
 func (m Migrator) AlterColumn(value interface{}, field string) error {
 	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
 		if field := stmt.Schema.LookUpField(field); field != nil {
-			fileType := clause.Expr{SQL: m.DataTypeOf(field)}
-			if field.NotNull {
-				fileType.SQL += " NOT NULL"
+			columnTypes, err := m.DB.Migrator().ColumnTypes(value)
+			if err != nil {
+				return err
 			}
 
-			return m.DB.Exec(
-				"ALTER TABLE ? ALTER COLUMN ? ?",
-				clause.Table{Name: stmt.Table}, clause.Column{Name: field.DBName}, fileType,
-			).Error
+			var current *ColumnType
+			for _, columnType := range columnTypes {
+				if ct, ok := columnType.(ColumnType); ok && ct.Name() == field.DBName {
+					current = &ct
+					break
+				}
+			}
+
+			if current == nil {
+				return fmt.Errorf("failed to look up column %s of table %s", field.DBName, m.currentTable(stmt))
+			}
+
+			fileType := clause.Expr{SQL: m.alterDataTypeOf(field, *current)}
+			history, temporal, err := m.historyTableOf(stmt)
+			if err != nil {
+				return err
+			}
+
+			return m.DB.Transaction(func(tx *gorm.DB) error {
+				table := clause.Table{Name: m.currentTable(stmt)}
+				dependencies, err := m.columnDependencies(tx, table.Name, field.DBName)
+				if err != nil {
+					return err
+				}
+
+				for _, dependency := range dependencies {
+					if dependency.Kind == "INDEX" || dependency.Kind == "UNIQUE" {
+						return fmt.Errorf("failed to alter column %s of table %s, %s %s depends on it, drop it before altering the column", field.DBName, table.Name, strings.ToLower(dependency.Kind), dependency.Name)
+					}
+				}
+
+				if err := m.dropColumnDependencies(tx, table, dependencies); err != nil {
+					return err
+				}
+
+				// the columns of system-versioned tables and their history tables are altered with
+				// the system versioning turned off
+				if temporal {
+					if err := tx.Exec("ALTER TABLE ? SET (SYSTEM_VERSIONING = OFF)", table).Error; err != nil {
+						return err
+					}
+
+					if err := tx.Exec(
+						"ALTER TABLE ? ALTER COLUMN ? ?",
+						clause.Table{Name: history}, clause.Column{Name: field.DBName}, fileType,
+					).Error; err != nil {
+						return err
+					}
+				}
+
+				if err := tx.Exec(
+					"ALTER TABLE ? ALTER COLUMN ? ?",
+					table, clause.Column{Name: field.DBName}, fileType,
+				).Error; err != nil {
+					return err
+				}
+
+				for _, dependency := range dependencies {
+					switch dependency.Kind {
+					case "DEFAULT":
+						// replaced by the default of the field below
+						if _, ok := m.defaultValueOf(field); !ok {
+							err = tx.Exec(
+								"ALTER TABLE ? ADD CONSTRAINT ? DEFAULT ? FOR ?",
+								table, clause.Column{Name: dependency.Name}, clause.Expr{SQL: dependency.Definition}, clause.Column{Name: field.DBName},
+							).Error
+						}
+					case "CHECK":
+						err = tx.Exec(
+							"ALTER TABLE ? ADD CONSTRAINT ? CHECK ?",
+							table, clause.Column{Name: dependency.Name}, clause.Expr{SQL: dependency.Definition},
+						).Error
+					}
+
+					if err != nil {
+						return err
+					}
+				}
+
+				if defaultValue, ok := m.defaultValueOf(field); ok {
+					if err := tx.Exec(
+						"ALTER TABLE ? ADD CONSTRAINT ? DEFAULT ? FOR ?",
+						table, clause.Column{Name: m.defaultConstraintName(table.Name, field.DBName)}, clause.Expr{SQL: defaultValue}, clause.Column{Name: field.DBName},
+					).Error; err != nil {
+						return err
+					}
+				}
+
+				if temporal {
+					return tx.Exec("ALTER TABLE ? SET (SYSTEM_VERSIONING = ON (HISTORY_TABLE = ?))", table, clause.Table{Name: history}).Error
+				}
+				return nil
+			})
 		}
 		return fmt.Errorf("failed to look up field with name: %s", field)
 	})
 }
 
+// alterDataTypeOf returns the column definition of ALTER COLUMN, keeping the collation and
+// NOT NULL of the current column unless the field specifies them
+func (m Migrator) alterDataTypeOf(field *schema.Field, current ColumnType) string {
+	dataType := m.DataTypeOf(field)
+
+	// IDENTITY can't be added or removed by ALTER COLUMN
+	if idx := strings.Index(strings.ToUpper(dataType), " IDENTITY"); idx != -1 {
+		dataType = dataType[:idx]
+	}
+
+	if lowerType := strings.ToLower(dataType); strings.Contains(lowerType, "char") || strings.Contains(lowerType, "text") {
+		if collation, ok := field.TagSettings["COLLATE"]; ok && collation != "" {
+			dataType += " COLLATE " + collation
+		} else if collation, ok := current.Collation(); ok {
+			dataType += " COLLATE " + collation
+		}
+	}
+
+	if nullable, _ := current.Nullable(); field.NotNull || field.PrimaryKey || !nullable {
+		dataType += " NOT NULL"
+	} else {
+		dataType += " NULL"
+	}
+
+	return dataType
+}
+
+// columnDependency default constraint, check constraint or index depending on a column
+type columnDependency struct {
+	Name       string
+	Definition string
+	Kind       string
+}
+
+func (m Migrator) columnDependencies(tx *gorm.DB, table, column string) (dependencies []columnDependency, err error) {
+	err = tx.Raw(
+		`SELECT name, definition, 'DEFAULT' AS kind FROM sys.default_constraints
+WHERE parent_object_id = OBJECT_ID(?) AND parent_column_id = COLUMNPROPERTY(OBJECT_ID(?), ?, 'ColumnId')
+UNION ALL
+SELECT name, definition, 'CHECK' AS kind FROM sys.check_constraints
+WHERE parent_object_id = OBJECT_ID(?) AND (parent_column_id = COLUMNPROPERTY(OBJECT_ID(?), ?, 'ColumnId') OR CHARINDEX('[' + ? + ']', definition) > 0)
+UNION ALL
+SELECT DISTINCT i.name, NULL AS definition, CASE WHEN i.is_unique_constraint = 1 THEN 'UNIQUE' ELSE 'INDEX' END AS kind
+FROM sys.indexes AS i INNER JOIN sys.index_columns AS ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+WHERE i.object_id = OBJECT_ID(?) AND ic.column_id = COLUMNPROPERTY(OBJECT_ID(?), ?, 'ColumnId') AND i.is_primary_key = 0`,
+		table, table, column,
+		table, table, column, column,
+		table, table, column,
+	).Scan(&dependencies).Error
+	return
+}
+
+func (m Migrator) dropColumnDependencies(tx *gorm.DB, table clause.Table, dependencies []columnDependency) error {
+	for _, dependency := range dependencies {
+		var err error
+		if dependency.Kind == "INDEX" {
+			err = tx.Exec("DROP INDEX ? ON ?", clause.Column{Name: dependency.Name}, table).Error
+		} else {
+			err = tx.Exec("ALTER TABLE ? DROP CONSTRAINT ?", table, clause.Column{Name: dependency.Name}).Error
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateColumn alters the column when its type, length, precision, nullability or default differs
+// from the field
+func (m Migrator) MigrateColumn(value interface{}, field *schema.Field, columnType gorm.ColumnType) error {
+	// the period columns of temporal tables are generated
+	if _, ok := temporalTableOf(field.Schema); ok {
+		if start, end := periodColumns(m.DB.NamingStrategy); field.DBName == start || field.DBName == end {
+			return nil
+		}
+	}
+
+	ct, ok := columnType.(ColumnType)
+	if !ok {
+		return m.Migrator.MigrateColumn(value, field, columnType)
+	}
+
+	if _, computed := ct.Computed(); computed || field.IgnoreMigration {
+		return nil
+	}
+
+	// rowversion columns can't be altered, nor can columns be altered to rowversion
+	if ct.DatabaseTypeName() == "rowversion" || field.DataType == "rowversion" {
+		return nil
+	}
+
+	if m.columnChanged(field, ct) {
+		return m.DB.Migrator().AlterColumn(value, field.Name)
+	}
+	return nil
+}
+
+// columnChanged returns whether the column ct differs from the data type, the nullability or the
+// default of field
+func (m Migrator) columnChanged(field *schema.Field, ct ColumnType) bool {
+	dataType, args := splitDataType(m.DataTypeOf(field))
+	if dataType == "" {
+		return false
+	} else if dataType != ct.DatabaseTypeName() {
+		return true
+	}
+
+	if length, ok := ct.Length(); ok && len(args) > 0 {
+		size := int64(-1)
+		if !strings.EqualFold(args[0], "MAX") {
+			size, _ = strconv.ParseInt(args[0], 10, 64)
+		}
+
+		if size != length {
+			return true
+		}
+	}
+
+	if precision, scale, ok := ct.DecimalSize(); ok && len(args) > 0 {
+		var expectedScale int64
+		expectedPrecision, _ := strconv.ParseInt(args[0], 10, 64)
+		if len(args) > 1 {
+			expectedScale, _ = strconv.ParseInt(args[1], 10, 64)
+		}
+
+		if precision != expectedPrecision || scale != expectedScale {
+			return true
+		}
+	}
+
+	switch dataType {
+	case "datetime2", "datetimeoffset", "time":
+		// fractional seconds precision, 7 when not specified
+		expectedScale := int64(7)
+		if len(args) > 0 {
+			expectedScale, _ = strconv.ParseInt(args[0], 10, 64)
+		}
+
+		if ct.scale != expectedScale {
+			return true
+		}
+	}
+
+	// NOT NULL columns are kept NOT NULL by AlterColumn
+	if nullable, _ := ct.Nullable(); nullable && (field.NotNull || field.PrimaryKey) {
+		return true
+	}
+
+	if defaultValue, ok := m.defaultValueOf(field); ok {
+		_, definition, hasDefault := ct.DefaultConstraint()
+		return !hasDefault || !strings.EqualFold(normalizeDefault(definition), normalizeDefault(defaultValue))
+	}
+	return false
+}
+
+// splitDataType splits a data type such as decimal(10,2) or bigint IDENTITY(1,1) into its lower case
+// name and its arguments
+func splitDataType(dataType string) (name string, args []string) {
+	dataType = strings.TrimSpace(dataType)
+	idx := strings.IndexAny(dataType, "( ")
+	if idx == -1 {
+		return strings.ToLower(dataType), nil
+	}
+
+	name = strings.ToLower(dataType[:idx])
+	if dataType[idx] == '(' {
+		if end := strings.IndexByte(dataType[idx:], ')'); end != -1 {
+			for _, arg := range strings.Split(dataType[idx+1:idx+end], ",") {
+				args = append(args, strings.TrimSpace(arg))
+			}
+		}
+	}
+	return
+}
+
+// normalizeDefault strips the parentheses and the N prefix of Unicode strings SQL Server adds to the
+// stored definitions of default constraints
+func normalizeDefault(definition string) string {
+	definition = strings.TrimSpace(definition)
+	for len(definition) > 1 && definition[0] == '(' && closingParenthesis(definition) == len(definition)-1 {
+		definition = strings.TrimSpace(definition[1 : len(definition)-1])
+	}
+
+	if strings.HasPrefix(definition, "N'") {
+		definition = definition[1:]
+	}
+	return definition
+}
+
+// closingParenthesis returns the index of the parenthesis closing the one starting s, ignoring the
+// ones of string literals
+func closingParenthesis(s string) int {
+	var depth int
+	var quoted bool
+	for idx, r := range s {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			depth++
+		case r == ')':
+			if depth--; depth == 0 {
+				return idx
+			}
+		}
+	}
+	return -1
+}
+
+func (m Migrator) ColumnTypes(value interface{}) ([]gorm.ColumnType, error) {
+	columnTypes := make([]gorm.ColumnType, 0)
+	execErr := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		rows, err := m.DB.Raw(
+			`SELECT c.name, TYPE_NAME(c.user_type_id), c.max_length, c.precision, c.scale, c.is_nullable, c.collation_name,
+	c.is_identity, CAST(ic.seed_value AS bigint), CAST(ic.increment_value AS bigint),
+	c.is_computed, cc.definition, dc.name, dc.definition
+FROM sys.columns AS c
+LEFT JOIN sys.identity_columns AS ic ON ic.object_id = c.object_id AND ic.column_id = c.column_id
+LEFT JOIN sys.computed_columns AS cc ON cc.object_id = c.object_id AND cc.column_id = c.column_id
+LEFT JOIN sys.default_constraints AS dc ON dc.parent_object_id = c.object_id AND dc.parent_column_id = c.column_id
+WHERE c.object_id = OBJECT_ID(?)
+ORDER BY c.column_id`,
+			m.currentTable(stmt),
+		).Rows()
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var ct ColumnType
+			if err := rows.Scan(
+				&ct.name, &ct.dataType, &ct.maxLength, &ct.precision, &ct.scale, &ct.nullable, &ct.collation,
+				&ct.identity, &ct.identitySeed, &ct.identityIncrement,
+				&ct.computed, &ct.computedDefinition, &ct.defaultName, &ct.defaultDefinition,
+			); err != nil {
+				return err
+			}
+			columnTypes = append(columnTypes, ct)
+		}
+
+		return rows.Err()
+	})
+
+	return columnTypes, execErr
+}
+
 func (m Migrator) RenameColumn(value interface{}, oldName, newName string) error {
 	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
 		if field := stmt.Schema.LookUpField(oldName); field != nil {
@@ -130,7 +700,7 @@ func (m Migrator) RenameColumn(value interface{}, oldName, newName string) error
 
 		return m.DB.Exec(
 			"sp_rename @objname = ?, @newname = ?, @objtype = 'COLUMN';",
-			fmt.Sprintf("%s.%s", stmt.Table, oldName), clause.Column{Name: newName},
+			fmt.Sprintf("%s.%s", m.currentTable(stmt), oldName), clause.Column{Name: newName},
 		).Error
 	})
 }
@@ -144,7 +714,7 @@ func (m Migrator) HasIndex(value interface{}, name string) bool {
 
 		return m.DB.Raw(
 			"SELECT count(*) FROM sys.indexes WHERE name=? AND object_id=OBJECT_ID(?)",
-			name, stmt.Table,
+			name, m.currentTable(stmt),
 		).Row().Scan(&count)
 	})
 	return count > 0
@@ -155,7 +725,7 @@ func (m Migrator) RenameIndex(value interface{}, oldName, newName string) error
 
 		return m.DB.Exec(
 			"sp_rename @objname = ?, @newname = ?, @objtype = 'INDEX';",
-			fmt.Sprintf("%s.%s", stmt.Table, oldName), clause.Column{Name: newName},
+			fmt.Sprintf("%s.%s", m.currentTable(stmt), oldName), clause.Column{Name: newName},
 		).Error
 	})
 }
@@ -163,22 +733,343 @@ func (m Migrator) RenameIndex(value interface{}, oldName, newName string) error
 func (m Migrator) HasConstraint(value interface{}, name string) bool {
 	var count int64
 	m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		constraint, chk, table := m.GuessConstraintAndTable(stmt, name)
+		constraint, chk, table := m.guessConstraintAndTable(stmt, name)
 		if constraint != nil {
 			name = constraint.Name
 		} else if chk != nil {
 			name = chk.Name
+		} else if field := m.lookUpDefaultField(stmt, name); field != nil {
+			return m.DB.Raw(
+				"SELECT count(*) FROM sys.default_constraints WHERE parent_object_id = OBJECT_ID(?) AND parent_column_id = COLUMNPROPERTY(OBJECT_ID(?), ?, 'ColumnId');",
+				table, table, field.DBName,
+			).Row().Scan(&count)
 		}
 
 		return m.DB.Raw(
-			`SELECT count(*) FROM sys.foreign_keys as F inner join sys.tables as T on F.parent_object_id=T.object_id inner join information_schema.tables as I on I.TABLE_NAME = T.name WHERE F.name = ?  AND T.Name = ? AND I.TABLE_CATALOG = ?;`,
-			name, table, m.CurrentDatabase(),
+			`SELECT count(*) FROM (
+	SELECT name, parent_object_id FROM sys.foreign_keys
+	UNION ALL SELECT name, parent_object_id FROM sys.check_constraints
+	UNION ALL SELECT name, parent_object_id FROM sys.key_constraints
+	UNION ALL SELECT name, parent_object_id FROM sys.default_constraints
+) AS c WHERE c.name = ? AND c.parent_object_id = OBJECT_ID(?);`,
+			name, table,
 		).Row().Scan(&count)
 	})
 	return count > 0
 }
 
+func (m Migrator) CreateConstraint(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		constraint, chk, table := m.guessConstraintAndTable(stmt, name)
+		if chk != nil {
+			return m.DB.Exec(
+				"ALTER TABLE ? ADD CONSTRAINT ? CHECK (?)",
+				clause.Table{Name: table}, clause.Column{Name: chk.Name}, clause.Expr{SQL: chk.Constraint},
+			).Error
+		}
+
+		if constraint == nil {
+			if field := m.lookUpDefaultField(stmt, name); field != nil {
+				defaultValue, _ := m.defaultValueOf(field)
+				return m.DB.Exec(
+					"ALTER TABLE ? ADD CONSTRAINT ? DEFAULT ? FOR ?",
+					clause.Table{Name: table}, clause.Column{Name: m.defaultConstraintName(table, field.DBName)}, clause.Expr{SQL: defaultValue}, clause.Column{Name: field.DBName},
+				).Error
+			}
+		}
+
+		return m.Migrator.CreateConstraint(value, name)
+	})
+}
+
+func (m Migrator) DropConstraint(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		constraint, chk, table := m.guessConstraintAndTable(stmt, name)
+		if constraint != nil {
+			name = constraint.Name
+		} else if chk != nil {
+			name = chk.Name
+		} else if field := m.lookUpDefaultField(stmt, name); field != nil {
+			// the default constraint may have been named by SQL Server
+			if err := m.DB.Raw(
+				"SELECT name FROM sys.default_constraints WHERE parent_object_id = OBJECT_ID(?) AND parent_column_id = COLUMNPROPERTY(OBJECT_ID(?), ?, 'ColumnId');",
+				table, table, field.DBName,
+			).Row().Scan(&name); err != nil {
+				return err
+			}
+		}
+
+		return m.DB.Exec("ALTER TABLE ? DROP CONSTRAINT ?", clause.Table{Name: table}, clause.Column{Name: name}).Error
+	})
+}
+
+// guessConstraintAndTable is GuessConstraintAndTable returning the schema qualified table of stmt
+// instead of its name
+func (m Migrator) guessConstraintAndTable(stmt *gorm.Statement, name string) (*schema.Constraint, *schema.Check, string) {
+	constraint, chk, table := m.GuessConstraintAndTable(stmt, name)
+	if table == stmt.Table {
+		table = m.currentTable(stmt)
+	}
+	return constraint, chk, table
+}
+
+// lookUpDefaultField returns the field having a default value matching name, either the field
+// name or the name of its default constraint
+func (m Migrator) lookUpDefaultField(stmt *gorm.Statement, name string) *schema.Field {
+	if stmt.Schema == nil {
+		return nil
+	}
+
+	field := stmt.Schema.LookUpField(name)
+	if field == nil {
+		for _, f := range stmt.Schema.Fields {
+			if f.DBName != "" && m.defaultConstraintName(m.currentTable(stmt), f.DBName) == name {
+				field = f
+				break
+			}
+		}
+	}
+
+	if field != nil {
+		if _, ok := m.defaultValueOf(field); ok {
+			return field
+		}
+	}
+	return nil
+}
+
 func (m Migrator) CurrentDatabase() (name string) {
 	m.DB.Raw("SELECT DB_NAME() AS [Current Database]").Row().Scan(&name)
 	return
 }
+
+// HasTableType returns whether the user-defined table type name exists
+func (m Migrator) HasTableType(name string) bool {
+	var count int
+	schema, name := m.splitTable(name)
+	m.DB.Raw(
+		"SELECT count(*) FROM sys.table_types WHERE name = ? AND schema_id = SCHEMA_ID(?)",
+		name, schema,
+	).Row().Scan(&count)
+	return count > 0
+}
+
+// CreateTableType creates the user-defined table type name with the columns of the struct value,
+// in the order of its fields, to bind slices of value with TVP. Like TVP, the columns are the top
+// level fields, embedded structs, relations and fields ignored by gorm must be tagged tvp:"-"
+func (m Migrator) CreateTableType(name string, value interface{}) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		fields, err := tableTypeFields(stmt.Schema)
+		if err != nil {
+			return err
+		}
+
+		schema, _ := m.splitTable(name)
+		if schema, ok := schema.(string); ok {
+			if err := m.createSchemaIfNotExists(schema); err != nil {
+				return err
+			}
+		}
+
+		var (
+			createTypeSQL = "CREATE TYPE ? AS TABLE ("
+			values        = []interface{}{clause.Table{Name: name}}
+		)
+
+		for idx, field := range fields {
+			if idx > 0 {
+				createTypeSQL += ","
+			}
+
+			// table types don't generate identity values of the bound rows
+			dataType := m.DataTypeOf(field)
+			if pos := strings.Index(strings.ToUpper(dataType), " IDENTITY"); pos != -1 {
+				dataType = dataType[:pos]
+			}
+			if field.NotNull {
+				dataType += " NOT NULL"
+			}
+
+			createTypeSQL += "? ?"
+			values = append(values, clause.Column{Name: field.DBName}, clause.Expr{SQL: dataType})
+		}
+		createTypeSQL += ")"
+
+		return m.DB.Exec(createTypeSQL, values...).Error
+	})
+}
+
+// tableTypeFields returns the fields bound by mssql.TVP, the top level fields of the struct not
+// skipped by the tvp or json tags, which must all be columns
+func tableTypeFields(s *schema.Schema) ([]*schema.Field, error) {
+	var fields []*schema.Field
+	for i := 0; i < s.ModelType.NumField(); i++ {
+		structField := s.ModelType.Field(i)
+		tvpTag, hasTVPTag := structField.Tag.Lookup("tvp")
+		jsonTag, hasJSONTag := structField.Tag.Lookup("json")
+		if mssql.IsSkipField(tvpTag, hasTVPTag, jsonTag, hasJSONTag) {
+			continue
+		}
+
+		field := s.FieldsByName[structField.Name]
+		if structField.PkgPath != "" || structField.Anonymous || field == nil || field.DBName == "" || len(field.StructField.Index) != 1 {
+			return nil, fmt.Errorf("field %s of %s isn't a column, tag it with tvp:\"-\" to create the table type without it", structField.Name, s.Name)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// DropTableType drops the user-defined table type name if it exists
+func (m Migrator) DropTableType(name string) error {
+	if !m.HasTableType(name) {
+		return nil
+	}
+	return m.DB.Exec("DROP TYPE ?", clause.Table{Name: name}).Error
+}
+
+// HasSnapshotIsolation returns whether ALLOW_SNAPSHOT_ISOLATION is enabled on the current database
+func (m Migrator) HasSnapshotIsolation() bool {
+	var state int
+	m.DB.Raw("SELECT snapshot_isolation_state FROM sys.databases WHERE name = DB_NAME()").Row().Scan(&state)
+	return state == 1
+}
+
+// EnableSnapshotIsolation enables ALLOW_SNAPSHOT_ISOLATION on the current database
+func (m Migrator) EnableSnapshotIsolation() error {
+	return m.DB.Exec("ALTER DATABASE CURRENT SET ALLOW_SNAPSHOT_ISOLATION ON").Error
+}
+
+// HasReadCommittedSnapshot returns whether READ_COMMITTED_SNAPSHOT is enabled on the current database
+func (m Migrator) HasReadCommittedSnapshot() bool {
+	var enabled bool
+	m.DB.Raw("SELECT is_read_committed_snapshot_on FROM sys.databases WHERE name = DB_NAME()").Row().Scan(&enabled)
+	return enabled
+}
+
+// EnableReadCommittedSnapshot enables READ_COMMITTED_SNAPSHOT on the current database, which waits
+// for the other connections to the database to be closed
+func (m Migrator) EnableReadCommittedSnapshot() error {
+	return m.DB.Exec("ALTER DATABASE CURRENT SET READ_COMMITTED_SNAPSHOT ON").Error
+}
+
+// SecurityPredicate filter or block predicate of a security policy, calling the inline
+// table-valued Function with the Columns of the Table model or name
+type SecurityPredicate struct {
+	Block     bool
+	Function  string
+	Table     interface{}
+	Columns   []string
+	Operation string // AFTER INSERT, AFTER UPDATE, BEFORE UPDATE or BEFORE DELETE of block predicates
+}
+
+// CreatePredicateFunction creates the schema bound inline table-valued function name returning a
+// row when condition holds for the parameters
+//
+//	m.CreatePredicateFunction("security.tenant_predicate", "@tenant_id int",
+//		"@tenant_id = CAST(SESSION_CONTEXT(N'tenant_id') AS int)")
+func (m Migrator) CreatePredicateFunction(name, parameters, condition string) error {
+	schema, _ := m.splitTable(name)
+	if schema, ok := schema.(string); ok {
+		if err := m.createSchemaIfNotExists(schema); err != nil {
+			return err
+		}
+	}
+
+	return m.DB.Exec(
+		fmt.Sprintf("CREATE FUNCTION ?(%s) RETURNS TABLE WITH SCHEMABINDING AS RETURN SELECT 1 AS result WHERE %s", parameters, condition),
+		clause.Table{Name: name},
+	).Error
+}
+
+// DropPredicateFunction drops the predicate function name if it exists
+func (m Migrator) DropPredicateFunction(name string) error {
+	return m.DB.Exec("DROP FUNCTION IF EXISTS ?", clause.Table{Name: name}).Error
+}
+
+// HasSecurityPolicy returns whether the security policy name exists
+func (m Migrator) HasSecurityPolicy(name string) bool {
+	var count int
+	schema, name := m.splitTable(name)
+	m.DB.Raw(
+		"SELECT count(*) FROM sys.security_policies WHERE name = ? AND schema_id = SCHEMA_ID(?)",
+		name, schema,
+	).Row().Scan(&count)
+	return count > 0
+}
+
+// CreateSecurityPolicy creates the enabled security policy name with predicates
+//
+//	m.CreateSecurityPolicy("security.tenant_policy",
+//		sqlserver.SecurityPredicate{Function: "security.tenant_predicate", Table: &Order{}, Columns: []string{"TenantID"}},
+//		sqlserver.SecurityPredicate{Block: true, Function: "security.tenant_predicate", Table: &Order{}, Columns: []string{"TenantID"}, Operation: "AFTER INSERT"},
+//	)
+func (m Migrator) CreateSecurityPolicy(name string, predicates ...SecurityPredicate) error {
+	var (
+		createPolicySQL = "CREATE SECURITY POLICY ?"
+		values          = []interface{}{clause.Table{Name: name}}
+	)
+
+	for idx, predicate := range predicates {
+		if idx > 0 {
+			createPolicySQL += ","
+		}
+
+		if predicate.Block {
+			createPolicySQL += " ADD BLOCK PREDICATE ?("
+		} else {
+			createPolicySQL += " ADD FILTER PREDICATE ?("
+		}
+		values = append(values, clause.Table{Name: predicate.Function})
+
+		table, columns := m.predicateColumns(predicate)
+		for i, column := range columns {
+			if i > 0 {
+				createPolicySQL += ","
+			}
+			createPolicySQL += "?"
+			values = append(values, column)
+		}
+
+		createPolicySQL += ") ON ?"
+		values = append(values, table)
+
+		if predicate.Block && predicate.Operation != "" {
+			createPolicySQL += " " + predicate.Operation
+		}
+	}
+	createPolicySQL += " WITH (STATE = ON)"
+
+	return m.DB.Exec(createPolicySQL, values...).Error
+}
+
+// predicateColumns returns the table and the columns of predicate, looking up the fields of models
+func (m Migrator) predicateColumns(predicate SecurityPredicate) (table clause.Table, columns []clause.Column) {
+	if name, ok := predicate.Table.(string); ok {
+		table = clause.Table{Name: name}
+		for _, column := range predicate.Columns {
+			columns = append(columns, clause.Column{Name: column})
+		}
+		return
+	}
+
+	m.RunWithValue(predicate.Table, func(stmt *gorm.Statement) error {
+		table = clause.Table{Name: m.currentTable(stmt)}
+		for _, column := range predicate.Columns {
+			if field := stmt.Schema.LookUpField(column); field != nil {
+				column = field.DBName
+			}
+			columns = append(columns, clause.Column{Name: column})
+		}
+		return nil
+	})
+	return
+}
+
+// DropSecurityPolicy drops the security policy name if it exists
+func (m Migrator) DropSecurityPolicy(name string) error {
+	if !m.HasSecurityPolicy(name) {
+		return nil
+	}
+	return m.DB.Exec("DROP SECURITY POLICY ?", clause.Table{Name: name}).Error
+}
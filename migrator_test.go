@@ -0,0 +1,171 @@
+package sqlserver
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+type migrateColumnModel struct {
+	ID       uint
+	Count    int     `gorm:"default:1"`
+	Amount   float64 `gorm:"precision:10"`
+	Price    float64 `gorm:"precision:10;scale:2"`
+	Name     string  `gorm:"size:100;not null;default:'none'"`
+	Note     string
+	Optional *string `gorm:"size:20"`
+}
+
+func TestColumnChanged(t *testing.T) {
+	db := openFake(t, Config{}, newFakeConnPool(t))
+	m := db.Migrator().(Migrator)
+
+	s, err := schema.Parse(&migrateColumnModel{}, &sync.Map{}, db.NamingStrategy)
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error %v", err)
+	}
+
+	defaultOf := func(definition string) (sql.NullString, sql.NullString) {
+		return sql.NullString{String: "DF_x", Valid: true}, sql.NullString{String: definition, Valid: true}
+	}
+
+	countDefaultName, countDefault := defaultOf("((1))")
+	nameDefaultName, nameDefault := defaultOf("(N'none')")
+	changedDefaultName, changedDefault := defaultOf("((2))")
+
+	tests := []struct {
+		field   string
+		column  ColumnType
+		changed bool
+	}{
+		{"Count", ColumnType{dataType: "bigint", precision: 19, nullable: true, defaultName: countDefaultName, defaultDefinition: countDefault}, false},
+		{"Count", ColumnType{dataType: "bigint", precision: 19, nullable: true, defaultName: changedDefaultName, defaultDefinition: changedDefault}, true},
+		{"Count", ColumnType{dataType: "bigint", precision: 19, nullable: true}, true},
+		{"Count", ColumnType{dataType: "smallint", precision: 5, nullable: true, defaultName: countDefaultName, defaultDefinition: countDefault}, true},
+		{"Amount", ColumnType{dataType: "decimal", precision: 10, nullable: true}, false},
+		{"Amount", ColumnType{dataType: "decimal", precision: 12, nullable: true}, true},
+		{"Price", ColumnType{dataType: "decimal", precision: 10, scale: 2, nullable: true}, false},
+		{"Price", ColumnType{dataType: "decimal", precision: 10, scale: 4, nullable: true}, true},
+		{"Name", ColumnType{dataType: "nvarchar", maxLength: 200, defaultName: nameDefaultName, defaultDefinition: nameDefault}, false},
+		{"Name", ColumnType{dataType: "nvarchar", maxLength: 200, nullable: true, defaultName: nameDefaultName, defaultDefinition: nameDefault}, true},
+		{"Name", ColumnType{dataType: "nvarchar", maxLength: 100, defaultName: nameDefaultName, defaultDefinition: nameDefault}, true},
+		{"Note", ColumnType{dataType: "nvarchar", maxLength: -1, nullable: true}, false},
+		{"Note", ColumnType{dataType: "nvarchar", maxLength: 8000, nullable: true}, true},
+		{"Optional", ColumnType{dataType: "nvarchar", maxLength: 40}, false},
+		{"ID", ColumnType{dataType: "bigint", precision: 19, identity: true}, false},
+	}
+
+	for _, test := range tests {
+		if changed := m.columnChanged(s.LookUpField(test.field), test.column); changed != test.changed {
+			t.Errorf("expects column %v of type %v to be changed %v, got %v", test.field, test.column.dataType, test.changed, changed)
+		}
+	}
+}
+
+type rowVersionModel struct {
+	ID      uint
+	Version RowVersion
+}
+
+func TestMigrateColumnKeepsRowVersion(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db := openFake(t, Config{}, pool)
+
+	s, err := schema.Parse(&rowVersionModel{}, &sync.Map{}, db.NamingStrategy)
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error %v", err)
+	}
+
+	if name := (ColumnType{dataType: "timestamp"}).DatabaseTypeName(); name != "rowversion" {
+		t.Errorf("expects timestamp columns to be reported as rowversion, got %v", name)
+	}
+
+	if err := db.Migrator().MigrateColumn(&rowVersionModel{}, s.LookUpField("Version"), ColumnType{name: "version", dataType: "timestamp", maxLength: 8}); err != nil {
+		t.Errorf("failed to migrate rowversion column, got error %v", err)
+	}
+
+	if queries := pool.recorded(); len(queries) > 0 {
+		t.Errorf("rowversion columns should never be altered, got %v", queries)
+	}
+}
+
+type probeOrder struct {
+	ID   uint
+	Code string
+}
+
+// findStatement returns the first statement containing query
+func findStatement(t *testing.T, statements []fakeStatement, query string) fakeStatement {
+	t.Helper()
+	for _, stmt := range statements {
+		if strings.Contains(stmt.query, query) {
+			return stmt
+		}
+	}
+
+	t.Errorf("expects a statement containing %q, got %v", query, statements)
+	return fakeStatement{}
+}
+
+func TestMigratorDefaultSchema(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db := openFake(t, Config{DefaultSchema: "sales"}, pool)
+	count := func(n int64) {
+		pool.returns([]string{"count"}, [][]driver.Value{{n}})
+	}
+
+	// the current database, the table and the schema don't exist
+	count(0)
+	count(0)
+	count(0)
+	if err := db.Migrator().AutoMigrate(&probeOrder{}); err != nil {
+		t.Fatalf("failed to migrate, got error %v", err)
+	}
+
+	statements := pool.recordedStatements()
+	if stmt := findStatement(t, statements, "FROM INFORMATION_SCHEMA.tables"); len(stmt.args) < 2 || !reflect.DeepEqual(stmt.args[:2], []interface{}{"probe_orders", "sales"}) {
+		t.Errorf("expects the table to be looked up in its schema, got %v", stmt.args)
+	}
+	findStatement(t, statements, `CREATE SCHEMA "sales"`)
+	findStatement(t, statements, `CREATE TABLE "sales"."probe_orders"`)
+
+	if _, err := db.Migrator().ColumnTypes(&probeOrder{}); err != nil {
+		t.Fatalf("failed to read column types, got error %v", err)
+	}
+
+	if stmt := findStatement(t, pool.recordedStatements(), "FROM sys.columns"); !reflect.DeepEqual(stmt.args, []interface{}{"sales.probe_orders"}) {
+		t.Errorf("expects the columns of the schema qualified table, got %v", stmt.args)
+	}
+
+	if err := db.Migrator().DropTable(&probeOrder{}); err != nil {
+		t.Fatalf("failed to drop table, got error %v", err)
+	}
+
+	statements = pool.recordedStatements()
+	if stmt := findStatement(t, statements, "FROM sys.foreign_keys"); !reflect.DeepEqual(stmt.args, []interface{}{"sales.probe_orders"}) {
+		t.Errorf("expects the foreign keys of the schema qualified table, got %v", stmt.args)
+	}
+	findStatement(t, statements, `DROP TABLE IF EXISTS "sales"."probe_orders"`)
+
+	if err := db.Migrator().DropColumn(&probeOrder{}, "Code"); err != nil {
+		t.Fatalf("failed to drop column, got error %v", err)
+	}
+
+	statements = pool.recordedStatements()
+	if stmt := findStatement(t, statements, "FROM sys.default_constraints"); stmt.args == nil || stmt.args[0] != "sales.probe_orders" {
+		t.Errorf("expects the dependencies of the schema qualified table, got %v", stmt.args)
+	}
+	findStatement(t, statements, `ALTER TABLE "sales"."probe_orders" DROP COLUMN "code"`)
+
+	count(0)
+	count(0)
+	db.Table("archive.probe_orders").Migrator().HasTable(&probeOrder{})
+	if stmt := findStatement(t, pool.recordedStatements(), "FROM INFORMATION_SCHEMA.tables"); len(stmt.args) < 2 || !reflect.DeepEqual(stmt.args[:2], []interface{}{"probe_orders", "archive"}) {
+		t.Errorf("expects the table of DB.Table to be looked up in its schema, got %v", stmt.args)
+	}
+}
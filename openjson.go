@@ -0,0 +1,180 @@
+package sqlserver
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+// openJSONIn IN condition binding its values as a single JSON array parameter, expanded by OPENJSON
+type openJSONIn struct {
+	Column interface{}
+	Values string
+}
+
+func (in openJSONIn) Build(builder clause.Builder) {
+	builder.WriteQuoted(in.Column)
+	builder.WriteString(" IN (SELECT value FROM OPENJSON(")
+	builder.AddVar(builder, in.Values)
+	builder.WriteString("))")
+}
+
+func (in openJSONIn) NegationBuild(builder clause.Builder) {
+	builder.WriteQuoted(in.Column)
+	builder.WriteString(" NOT IN (SELECT value FROM OPENJSON(")
+	builder.AddVar(builder, in.Values)
+	builder.WriteString("))")
+}
+
+// buildWhere builds the WHERE clause, IN conditions having more values than Config.OpenJSONThreshold
+// bind them as a single JSON array instead of a parameter per value
+func (dialector Dialector) buildWhere(c clause.Clause, builder clause.Builder) {
+	c.Builder = nil
+	if c.Expression != nil {
+		c.Expression = dialector.rewriteIn(c.Expression)
+	}
+	c.Build(builder)
+}
+
+func (dialector Dialector) rewriteIn(expr clause.Expression) clause.Expression {
+	switch expr := expr.(type) {
+	case clause.Where:
+		return clause.Where{Exprs: dialector.rewriteInExprs(expr.Exprs)}
+	case clause.AndConditions:
+		return clause.AndConditions{Exprs: dialector.rewriteInExprs(expr.Exprs)}
+	case clause.OrConditions:
+		return clause.OrConditions{Exprs: dialector.rewriteInExprs(expr.Exprs)}
+	case clause.NotConditions:
+		return clause.NotConditions{Exprs: dialector.rewriteInExprs(expr.Exprs)}
+	case clause.IN:
+		if len(expr.Values) > dialector.OpenJSONThreshold {
+			if values, ok := jsonArrayOf(expr.Values); ok {
+				return openJSONIn{Column: expr.Column, Values: values}
+			}
+		}
+	case clause.Expr:
+		return dialector.rewriteInExpr(expr)
+	}
+	return expr
+}
+
+func (dialector Dialector) rewriteInExprs(exprs []clause.Expression) []clause.Expression {
+	rewritten := make([]clause.Expression, len(exprs))
+	for idx, expr := range exprs {
+		rewritten[idx] = dialector.rewriteIn(expr)
+	}
+	return rewritten
+}
+
+// rewriteInExpr rewrites the `IN ?` and `IN (?)` placeholders of expr bound to slices
+func (dialector Dialector) rewriteInExpr(expr clause.Expr) clause.Expr {
+	var (
+		sql  strings.Builder
+		vars = make([]interface{}, 0, len(expr.Vars))
+		idx  int
+	)
+
+	for i := 0; i < len(expr.SQL); i++ {
+		if expr.SQL[i] != '?' || idx >= len(expr.Vars) {
+			sql.WriteByte(expr.SQL[i])
+			continue
+		}
+
+		v := expr.Vars[idx]
+		idx++
+
+		if parenthesis, ok := followsIn(sql.String()); ok && (!parenthesis || strings.HasPrefix(expr.SQL[i+1:], ")")) {
+			if values, ok := sliceValues(v); ok && len(values) > dialector.OpenJSONThreshold {
+				if array, ok := jsonArrayOf(values); ok {
+					if !parenthesis {
+						sql.WriteString("(SELECT value FROM OPENJSON(?))")
+					} else {
+						sql.WriteString("SELECT value FROM OPENJSON(?)")
+					}
+					vars = append(vars, array)
+					continue
+				}
+			}
+		}
+
+		sql.WriteByte('?')
+		vars = append(vars, v)
+	}
+
+	if idx == 0 {
+		return expr
+	}
+
+	return clause.Expr{SQL: sql.String(), Vars: append(vars, expr.Vars[idx:]...), WithoutParentheses: expr.WithoutParentheses}
+}
+
+// followsIn returns whether sql ends with the IN operator, optionally followed by a parenthesis
+func followsIn(sql string) (parenthesis bool, ok bool) {
+	sql = strings.TrimRight(sql, " \t\n")
+	if strings.HasSuffix(sql, "(") {
+		parenthesis = true
+		sql = strings.TrimRight(sql[:len(sql)-1], " \t\n")
+	}
+
+	if len(sql) < 3 || !strings.EqualFold(sql[len(sql)-2:], "IN") {
+		return false, false
+	}
+
+	switch sql[len(sql)-3] {
+	case ' ', '\t', '\n', ')':
+		return parenthesis, true
+	}
+	return false, false
+}
+
+func sliceValues(v interface{}) ([]interface{}, bool) {
+	if _, ok := v.(driver.Valuer); ok {
+		return nil, false
+	}
+
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return nil, false
+		}
+
+		values := make([]interface{}, rv.Len())
+		for i := range values {
+			values[i] = rv.Index(i).Interface()
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// jsonArrayOf encodes values as a JSON array, only numbers, strings and booleans compare the same
+// once returned by OPENJSON
+func jsonArrayOf(values []interface{}) (string, bool) {
+	resolved := make([]interface{}, len(values))
+	for idx, value := range values {
+		if valuer, ok := value.(driver.Valuer); ok {
+			var err error
+			if value, err = valuer.Value(); err != nil {
+				return "", false
+			}
+		}
+
+		switch reflect.ValueOf(value).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64, reflect.String, reflect.Bool:
+			resolved[idx] = value
+		default:
+			return "", false
+		}
+	}
+
+	array, err := json.Marshal(resolved)
+	if err != nil {
+		return "", false
+	}
+	return string(array), true
+}
@@ -0,0 +1,93 @@
+package sqlserver
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type openJSONUser struct {
+	ID   uint
+	Name string
+}
+
+func TestOpenJSONIn(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db := openFake(t, Config{OpenJSONThreshold: 3}, pool)
+
+	pool.returns([]string{"id", "name"}, [][]driver.Value{{int64(1), "a"}, {int64(4), "d"}})
+
+	var users []openJSONUser
+	if err := db.Where("id IN ?", []int{1, 2, 3, 4}).Find(&users).Error; err != nil {
+		t.Fatalf("failed to query, got error %v", err)
+	}
+
+	if len(users) != 2 || users[0].ID != 1 || users[1].ID != 4 {
+		t.Errorf("expects the matching users to be found, got %+v", users)
+	}
+
+	if statements := pool.recordedStatements(); len(statements) != 1 ||
+		statements[0].query != `SELECT * FROM "open_json_users" WHERE id IN (SELECT value FROM OPENJSON(@p1))` ||
+		!reflect.DeepEqual(statements[0].args, []interface{}{"[1,2,3,4]"}) {
+		t.Errorf("expects the values to be bound as a JSON array, got %v", statements)
+	}
+
+	tests := []struct {
+		query func()
+		sql   string
+		args  []interface{}
+	}{
+		{
+			func() { db.Where("name IN (?) AND id > ?", []string{"a", "b", "c", "d"}, 1).Find(&users) },
+			`SELECT * FROM "open_json_users" WHERE name IN (SELECT value FROM OPENJSON(@p1)) AND id > @p2`,
+			[]interface{}{`["a","b","c","d"]`, 1},
+		},
+		{
+			func() { db.Find(&users, []uint{1, 2, 3, 4}) },
+			`SELECT * FROM "open_json_users" WHERE "open_json_users"."id" IN (SELECT value FROM OPENJSON(@p1))`,
+			[]interface{}{"[1,2,3,4]"},
+		},
+		{
+			func() { db.Not(map[string]interface{}{"name": []string{"a", "b", "c", "d"}}).Find(&users) },
+			`SELECT * FROM "open_json_users" WHERE "name" NOT IN (SELECT value FROM OPENJSON(@p1))`,
+			[]interface{}{`["a","b","c","d"]`},
+		},
+		{
+			func() { db.Delete(&openJSONUser{}, []uint{1, 2, 3, 4}) },
+			`DELETE FROM "open_json_users" WHERE "open_json_users"."id" IN (SELECT value FROM OPENJSON(@p1))`,
+			[]interface{}{"[1,2,3,4]"},
+		},
+		{
+			func() { db.Where("id IN ?", []int{1, 2, 3}).Find(&users) },
+			`SELECT * FROM "open_json_users" WHERE id IN (@p1,@p2,@p3)`,
+			[]interface{}{1, 2, 3},
+		},
+		{
+			func() {
+				now := time.Now()
+				db.Where("created_at IN ?", []time.Time{now, now, now, now}).Find(&users)
+			},
+			`SELECT * FROM "open_json_users" WHERE created_at IN (@p1,@p2,@p3,@p4)`,
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		test.query()
+
+		statements := pool.recordedStatements()
+		var stmt fakeStatement
+		for _, s := range statements {
+			if s.query != "BEGIN TRANSACTION" && s.query != "COMMIT TRANSACTION" {
+				stmt = s
+			}
+		}
+
+		if stmt.query != test.sql {
+			t.Errorf("expects %v, got %v", test.sql, statements)
+		} else if test.args != nil && !reflect.DeepEqual(stmt.args, test.args) {
+			t.Errorf("expects %v to be bound to %v, got %v", test.sql, test.args, stmt.args)
+		}
+	}
+}
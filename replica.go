@@ -0,0 +1,143 @@
+package sqlserver
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	readOnlyRoutedKey = "sqlserver:read_only_routed"
+	// readOnlyRetryInterval time the primary serves the read-only queries once the secondary
+	// has been unreachable
+	readOnlyRetryInterval = 30 * time.Second
+)
+
+// Primary forces the statement to the primary replica when Config.ReadOnlyIntent routes the
+// read-only queries to a readable secondary
+//
+//	db.Clauses(sqlserver.Primary()).First(&user)
+func Primary() clause.Expression {
+	return primary{}
+}
+
+type primary struct{}
+
+func (primary) Name() string {
+	return "PRIMARY"
+}
+
+func (primary) Build(clause.Builder) {}
+
+func (p primary) MergeClause(c *clause.Clause) {
+	c.Expression = p
+}
+
+// readOnlyDSN adds ApplicationIntent=ReadOnly to the URL, ODBC or ADO connection string dsn
+func readOnlyDSN(dsn string) (string, error) {
+	if strings.HasPrefix(dsn, "sqlserver://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", err
+		}
+
+		query := u.Query()
+		query.Set("ApplicationIntent", "ReadOnly")
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
+	return strings.TrimRight(dsn, "; ") + ";ApplicationIntent=ReadOnly", nil
+}
+
+// readOnlyRouter routes the read-only queries outside transactions to the secondary pool,
+// falling back to the primary pool while the secondary is unreachable. Exec always runs on the
+// primary pool
+type readOnlyRouter struct {
+	primary     gorm.ConnPool
+	secondary   gorm.ConnPool
+	unreachable int64
+}
+
+func (router *readOnlyRouter) route(db *gorm.DB) {
+	if db.Error != nil || db.DryRun || db.Statement.ConnPool != router.primary {
+		return
+	}
+
+	// locking and table hints such as UPDLOCK or READPAST only make sense on the primary
+	for _, name := range []string{"PRIMARY", "FOR", "TABLE HINTS"} {
+		if _, ok := db.Statement.Clauses[name]; ok {
+			return
+		}
+	}
+
+	// statements written by Raw, batches may write after selecting
+	if sql := strings.ToUpper(strings.TrimRight(strings.TrimSpace(db.Statement.SQL.String()), ";")); sql != "" &&
+		(!strings.HasPrefix(sql, "SELECT") || strings.Contains(sql, " INTO ") || strings.Contains(sql, ";")) {
+		return
+	}
+
+	if time.Now().UnixNano() < atomic.LoadInt64(&router.unreachable) {
+		return
+	}
+
+	db.Statement.ConnPool = router.secondary
+	db.InstanceSet(readOnlyRoutedKey, true)
+}
+
+// fallback restores the primary pool of the statement, re-executing it on the primary when the
+// secondary is unreachable
+func (router *readOnlyRouter) fallback(exec func(*gorm.DB)) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if routed, ok := db.InstanceGet(readOnlyRoutedKey); !ok || !routed.(bool) {
+			return
+		}
+
+		db.InstanceSet(readOnlyRoutedKey, false)
+		db.Statement.ConnPool = router.primary
+
+		if db.Error != nil && isConnectionError(db.Error) {
+			atomic.StoreInt64(&router.unreachable, time.Now().Add(readOnlyRetryInterval).UnixNano())
+			db.Error = nil
+			exec(db)
+		}
+	}
+}
+
+// isConnectionError returns whether err is a network error or a readable secondary refusing
+// connections
+func isConnectionError(err error) bool {
+	var (
+		netErr   net.Error
+		mssqlErr mssql.Error
+	)
+
+	if errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr) {
+		return true
+	} else if errors.As(err, &mssqlErr) {
+		switch mssqlErr.Number {
+		case 976, 978, 983, 4060:
+			return true
+		}
+	}
+	return false
+}
+
+func (router *readOnlyRouter) register(db *gorm.DB) {
+	db.Callback().Query().Before("gorm:query").Register("sqlserver:route_read_only", router.route)
+	db.Callback().Query().After("gorm:query").Register("sqlserver:read_only_fallback", router.fallback(withSessionContext(callbacks.Query)))
+	db.Callback().Row().Before("gorm:row").Register("sqlserver:route_read_only", router.route)
+	db.Callback().Row().After("gorm:row").Register("sqlserver:read_only_fallback", router.fallback(func(db *gorm.DB) {
+		// only Rows reports errors when querying, Row does when scanning
+		db.Statement.Settings.Store("rows", true)
+		rowWithSessionContext(callbacks.RowQuery)(db)
+	}))
+}
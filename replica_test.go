@@ -0,0 +1,124 @@
+package sqlserver
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+type replicaUser struct {
+	ID   uint
+	Name string
+}
+
+func openReplica(t *testing.T) (db *gorm.DB, primary, secondary *fakeConnPool) {
+	primary, secondary = newFakeConnPool(t), newFakeConnPool(t)
+	db = openFake(t, Config{ReadOnlyConn: secondary}, primary)
+	secondary.recorded()
+	return db, primary, secondary
+}
+
+// expectRouted checks the statements containing sql were sent to the expected pool only
+func expectRouted(t *testing.T, sql string, expected, other *fakeConnPool) {
+	t.Helper()
+
+	contains := func(queries []string) bool {
+		for _, query := range queries {
+			if strings.Contains(query, sql) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if queries := expected.recorded(); !contains(queries) {
+		t.Errorf("expects %q to be sent to the pool, got %v", sql, queries)
+	}
+
+	if queries := other.recorded(); contains(queries) {
+		t.Errorf("expects %q not to be sent to the other pool, got %v", sql, queries)
+	}
+}
+
+func TestReadOnlyRouting(t *testing.T) {
+	db, primary, secondary := openReplica(t)
+
+	if err := db.Find(&[]replicaUser{}).Error; err != nil {
+		t.Fatalf("failed to query, got error %v", err)
+	}
+	expectRouted(t, `SELECT * FROM "replica_users"`, secondary, primary)
+
+	db.Raw("SELECT name FROM replica_users WHERE id = ?", 1).Scan(&[]string{})
+	expectRouted(t, "SELECT name FROM replica_users", secondary, primary)
+
+	db.Raw("SELECT name INTO #names FROM replica_users").Scan(&[]string{})
+	expectRouted(t, "SELECT name INTO #names", primary, secondary)
+
+	db.Raw("SELECT name FROM replica_users; DELETE FROM replica_users").Scan(&[]string{})
+	expectRouted(t, "DELETE FROM replica_users", primary, secondary)
+
+	db.Exec("SELECT name FROM replica_users WHERE id = ?", 1)
+	expectRouted(t, "SELECT name FROM replica_users", primary, secondary)
+
+	db.Exec("SELECT 1; DELETE FROM replica_users")
+	expectRouted(t, "DELETE FROM replica_users", primary, secondary)
+
+	db.Create(&replicaUser{Name: "jinzhu"})
+	expectRouted(t, `INSERT INTO "replica_users"`, primary, secondary)
+}
+
+func TestReadOnlyRoutingToPrimary(t *testing.T) {
+	db, primary, secondary := openReplica(t)
+
+	db.Clauses(Primary()).Find(&[]replicaUser{})
+	expectRouted(t, `SELECT * FROM "replica_users"`, primary, secondary)
+
+	db.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&[]replicaUser{})
+	expectRouted(t, `SELECT * FROM "replica_users" WITH (UPDLOCK, ROWLOCK)`, primary, secondary)
+
+	db.Clauses(TableHint("READPAST")).Find(&[]replicaUser{})
+	expectRouted(t, `SELECT * FROM "replica_users" WITH (READPAST)`, primary, secondary)
+
+	db.Transaction(func(tx *gorm.DB) error {
+		return tx.Find(&[]replicaUser{}).Error
+	})
+	expectRouted(t, `SELECT * FROM "replica_users"`, primary, secondary)
+
+	db.Migrator().HasTable(&replicaUser{})
+	expectRouted(t, "INFORMATION_SCHEMA.tables", primary, secondary)
+
+	db.Migrator().ColumnTypes(&replicaUser{})
+	expectRouted(t, "FROM sys.columns", primary, secondary)
+}
+
+func TestReadOnlyFallback(t *testing.T) {
+	db, primary, secondary := openReplica(t)
+	secondary.fail(driver.ErrBadConn)
+
+	if err := db.Find(&[]replicaUser{}).Error; err != nil {
+		t.Fatalf("query should fall back to the primary, got error %v", err)
+	}
+
+	if queries := secondary.recorded(); len(queries) != 1 {
+		t.Errorf("expects the query to be sent to the secondary first, got %v", queries)
+	}
+
+	if queries := primary.recorded(); len(queries) != 1 || !strings.Contains(queries[0], `SELECT * FROM "replica_users"`) {
+		t.Errorf("expects the query to be re-executed on the primary, got %v", queries)
+	}
+
+	// the primary serves the read-only queries while the secondary is unreachable
+	db.Find(&[]replicaUser{})
+	expectRouted(t, `SELECT * FROM "replica_users"`, primary, secondary)
+}
+
+func TestReadOnlyIntentRequiresDSN(t *testing.T) {
+	_, err := gorm.Open(New(Config{Conn: newFakeConnPool(t), ReadOnlyIntent: true}), &gorm.Config{Logger: logger.Discard})
+	if err == nil {
+		t.Errorf("expects ReadOnlyIntent without DSN nor ReadOnlyConn to be rejected")
+	}
+}
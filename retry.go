@@ -0,0 +1,95 @@
+package sqlserver
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	"gorm.io/gorm"
+)
+
+// DefaultRetryableErrors deadlock victim and the transient errors returned by Azure SQL during failovers
+var DefaultRetryableErrors = []int32{1205, 4060, 40197, 40501, 40613, 49918, 49919, 49920, 10928, 10929}
+
+const retryAttemptKey = "sqlserver:retry_attempt"
+
+type RetryPolicy struct {
+	MaxAttempts     int
+	Backoff         func(attempt int) time.Duration
+	RetryableErrors []int32
+}
+
+func (policy *RetryPolicy) maxAttempts() int {
+	if policy == nil {
+		return 1
+	} else if policy.MaxAttempts <= 0 {
+		return 3
+	}
+	return policy.MaxAttempts
+}
+
+func (policy *RetryPolicy) backoff(attempt int) time.Duration {
+	if policy.Backoff != nil {
+		return policy.Backoff(attempt)
+	}
+	return (100 * time.Millisecond) << uint(attempt-1)
+}
+
+// IsRetryable returns true if err wraps a mssql.Error with one of the retryable error numbers
+func (policy *RetryPolicy) IsRetryable(err error) bool {
+	var mssqlErr mssql.Error
+	if policy == nil || !errors.As(err, &mssqlErr) {
+		return false
+	}
+
+	retryableErrors := policy.RetryableErrors
+	if len(retryableErrors) == 0 {
+		retryableErrors = DefaultRetryableErrors
+	}
+
+	for _, number := range retryableErrors {
+		if mssqlErr.Number == number {
+			return true
+		}
+	}
+	return false
+}
+
+// Transaction runs fc in a transaction, re-running the whole closure when it fails with an error
+// considered retryable by Config.RetryPolicy. Nested transactions are never retried, as the outer
+// transaction has been rolled back by SQL Server already
+func Transaction(db *gorm.DB, fc func(tx *gorm.DB) error, opts ...*sql.TxOptions) (err error) {
+	var policy *RetryPolicy
+	if dialector, ok := db.Dialector.(*Dialector); ok {
+		policy = dialector.RetryPolicy
+	}
+
+	if committer, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok && committer != nil {
+		return db.Transaction(fc, opts...)
+	}
+
+	maxAttempts := policy.maxAttempts()
+	for attempt := 1; ; attempt++ {
+		err = db.Set(retryAttemptKey, attempt).Transaction(fc, opts...)
+		if err == nil || attempt >= maxAttempts || !policy.IsRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-db.Statement.Context.Done():
+			return err
+		case <-time.After(policy.backoff(attempt)):
+		}
+	}
+}
+
+// RetryAttempt returns the current attempt of Transaction, starting from 1, or 0 outside of it
+func RetryAttempt(db *gorm.DB) int {
+	if v, ok := db.Get(retryAttemptKey); ok {
+		if attempt, ok := v.(int); ok {
+			return attempt
+		}
+	}
+	return 0
+}
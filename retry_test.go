@@ -0,0 +1,141 @@
+package sqlserver
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	"gorm.io/gorm"
+)
+
+func openRetry(t *testing.T, policy *RetryPolicy) (*gorm.DB, *fakeConnPool) {
+	pool := newFakeConnPool(t)
+	return openFake(t, Config{RetryPolicy: policy}, pool), pool
+}
+
+func noBackoff(int) time.Duration {
+	return 0
+}
+
+func TestTransactionRetriesDeadlock(t *testing.T) {
+	db, pool := openRetry(t, &RetryPolicy{Backoff: noBackoff})
+	pool.fail(nil, mssql.Error{Number: 1205, Message: "deadlock victim"})
+
+	var attempts []int
+	err := Transaction(db, func(tx *gorm.DB) error {
+		attempts = append(attempts, RetryAttempt(tx))
+		return tx.Exec("UPDATE users SET name = ?", "jinzhu").Error
+	})
+
+	if err != nil {
+		t.Fatalf("transaction should succeed after retrying, got error %v", err)
+	}
+
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+		t.Errorf("closure should see attempts [1 2], got %v", attempts)
+	}
+
+	expects := []string{
+		"BEGIN TRANSACTION", "UPDATE users SET name = @p1", "ROLLBACK TRANSACTION",
+		"BEGIN TRANSACTION", "UPDATE users SET name = @p1", "COMMIT TRANSACTION",
+	}
+	if queries := pool.recorded(); len(queries) != len(expects) {
+		t.Errorf("expects statements %v, got %v", expects, queries)
+	} else {
+		for idx, query := range queries {
+			if query != expects[idx] {
+				t.Errorf("expects statement %v to be %q, got %q", idx, expects[idx], query)
+			}
+		}
+	}
+}
+
+func TestTransactionDoesNotRetryOtherErrors(t *testing.T) {
+	db, pool := openRetry(t, &RetryPolicy{Backoff: noBackoff})
+	pool.fail(nil, mssql.Error{Number: 547, Message: "conflicted with the FOREIGN KEY constraint"})
+
+	var calls int
+	err := Transaction(db, func(tx *gorm.DB) error {
+		calls++
+		return tx.Exec("DELETE FROM users").Error
+	})
+
+	var mssqlErr mssql.Error
+	if !errors.As(err, &mssqlErr) || mssqlErr.Number != 547 {
+		t.Errorf("expects the constraint error, got %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("closure should run once, ran %v times", calls)
+	}
+}
+
+func TestTransactionMaxAttempts(t *testing.T) {
+	db, pool := openRetry(t, &RetryPolicy{MaxAttempts: 2, Backoff: noBackoff})
+	deadlock := mssql.Error{Number: 1205, Message: "deadlock victim"}
+	pool.fail(nil, deadlock, nil, nil, deadlock)
+
+	var calls int
+	err := Transaction(db, func(tx *gorm.DB) error {
+		calls++
+		return tx.Exec("UPDATE users SET name = ?", "jinzhu").Error
+	})
+
+	var mssqlErr mssql.Error
+	if !errors.As(err, &mssqlErr) || mssqlErr.Number != 1205 {
+		t.Errorf("expects the deadlock error once attempts are exhausted, got %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("closure should run MaxAttempts times, ran %v times", calls)
+	}
+}
+
+func TestTransactionWithoutRetryPolicy(t *testing.T) {
+	db, pool := openRetry(t, nil)
+	pool.fail(nil, mssql.Error{Number: 1205, Message: "deadlock victim"})
+
+	var calls int
+	err := Transaction(db, func(tx *gorm.DB) error {
+		calls++
+		if attempt := RetryAttempt(tx); attempt != 1 {
+			t.Errorf("expects attempt 1, got %v", attempt)
+		}
+		return tx.Exec("UPDATE users SET name = ?", "jinzhu").Error
+	})
+
+	if err == nil || calls != 1 {
+		t.Errorf("transaction should fail without retrying, got error %v after %v calls", err, calls)
+	}
+}
+
+func TestNestedTransactionIsNotRetried(t *testing.T) {
+	db, pool := openRetry(t, &RetryPolicy{Backoff: noBackoff})
+
+	var outer, inner int
+	err := Transaction(db, func(tx *gorm.DB) error {
+		outer++
+		if outer == 1 {
+			// the deadlock of the UPDATE of the nested transaction
+			pool.fail(nil, mssql.Error{Number: 1205, Message: "deadlock victim"})
+		}
+
+		return Transaction(tx, func(tx *gorm.DB) error {
+			inner++
+			return tx.Exec("UPDATE users SET name = ?", "jinzhu").Error
+		})
+	})
+
+	if err != nil {
+		t.Fatalf("outer transaction should succeed after retrying, got error %v", err)
+	}
+
+	if outer != 2 || inner != 2 {
+		t.Errorf("only the outer transaction should be retried, got %v outer and %v inner calls", outer, inner)
+	}
+
+	if RetryAttempt(db) != 0 {
+		t.Errorf("expects no attempt outside of Transaction, got %v", RetryAttempt(db))
+	}
+}
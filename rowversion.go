@@ -0,0 +1,105 @@
+package sqlserver
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrConcurrentModification the row was modified or deleted since its rowversion was read
+var ErrConcurrentModification = errors.New("concurrent modification, rowversion mismatch")
+
+const rowVersionCheckedKey = "sqlserver:rowversion_checked"
+
+// RowVersion rowversion column used for optimistic concurrency, never written by Create and
+// read back through OUTPUT INSERTED, updates and deletes of a model fail with
+// ErrConcurrentModification when the row has been changed since the rowversion was read
+//
+//	type Order struct {
+//		ID      uint
+//		Version sqlserver.RowVersion
+//	}
+type RowVersion []byte
+
+func (v *RowVersion) Scan(value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		*v = nil
+	case []byte:
+		*v = append((*v)[:0], value...)
+	default:
+		return fmt.Errorf("failed to scan %T into RowVersion", value)
+	}
+	return nil
+}
+
+func (v RowVersion) Value() (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return []byte(v), nil
+}
+
+func (RowVersion) GormDataType() string {
+	return "rowversion"
+}
+
+func (RowVersion) GormDBDataType(*gorm.DB, *schema.Field) string {
+	return "rowversion"
+}
+
+func rowVersionField(s *schema.Schema) *schema.Field {
+	if s != nil {
+		for _, field := range s.Fields {
+			if field.DataType == "rowversion" && field.DBName != "" {
+				return field
+			}
+		}
+	}
+	return nil
+}
+
+// omitRowVersion rowversion columns can't be written
+func omitRowVersion(db *gorm.DB) {
+	if field := rowVersionField(db.Statement.Schema); field != nil {
+		db.Statement.Omits = append(db.Statement.Omits, field.DBName)
+	}
+}
+
+// checkRowVersion restricts updates and deletes of a model to the rowversion it has been read with
+func checkRowVersion(db *gorm.DB) {
+	if db.Error != nil || db.Statement.SQL.Len() > 0 {
+		return
+	}
+
+	if field := rowVersionField(db.Statement.Schema); field != nil {
+		omitRowVersion(db)
+
+		if db.Statement.ReflectValue.Kind() == reflect.Struct {
+			if value, isZero := field.ValueOf(db.Statement.ReflectValue); !isZero {
+				db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+					clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: value},
+				}})
+				db.InstanceSet(rowVersionCheckedKey, true)
+			}
+		}
+	}
+}
+
+func verifyRowVersion(db *gorm.DB) {
+	if _, ok := db.InstanceGet(rowVersionCheckedKey); ok && db.Error == nil && !db.DryRun && db.RowsAffected == 0 {
+		db.AddError(ErrConcurrentModification)
+	}
+}
+
+func registerRowVersion(db *gorm.DB) {
+	db.Callback().Update().After("gorm:before_update").Before("gorm:update").Register("sqlserver:check_rowversion", checkRowVersion)
+	db.Callback().Update().After("gorm:update").Register("sqlserver:verify_rowversion", verifyRowVersion)
+	db.Callback().Delete().After("gorm:before_delete").Before("gorm:delete").Register("sqlserver:check_rowversion", checkRowVersion)
+	db.Callback().Delete().After("gorm:delete").Register("sqlserver:verify_rowversion", verifyRowVersion)
+}
@@ -0,0 +1,173 @@
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+)
+
+const sessionContextSetKey = "sqlserver:session_context_set"
+
+type sessionContextKey struct{}
+
+// WithSessionContext returns a copy of ctx whose statements run with values set by
+// sp_set_session_context, read by SESSION_CONTEXT(N'key') in row-level security predicates
+//
+//	ctx := sqlserver.WithSessionContext(ctx, map[string]interface{}{"tenant_id": 42})
+//	db.WithContext(ctx).Find(&orders)
+func WithSessionContext(ctx context.Context, values map[string]interface{}) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, values)
+}
+
+func sessionContextOf(ctx context.Context) map[string]interface{} {
+	if ctx == nil {
+		return nil
+	}
+	values, _ := ctx.Value(sessionContextKey{}).(map[string]interface{})
+	return values
+}
+
+// sessionContextSQL returns the batch of sp_set_session_context setting values, or resetting them
+// to NULL, its vars are numbered from offset
+func sessionContextSQL(values map[string]interface{}, reset bool, offset int) (string, []interface{}) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var (
+		batch strings.Builder
+		vars  = make([]interface{}, 0, len(keys)*2)
+	)
+	for _, key := range keys {
+		value := values[key]
+		if reset {
+			value = nil
+		}
+		vars = append(vars, key, value)
+		fmt.Fprintf(&batch, "EXEC sp_set_session_context @key = @p%d, @value = @p%d;", offset+len(vars)-1, offset+len(vars))
+	}
+	return batch.String(), vars
+}
+
+func setSessionContext(ctx context.Context, pool gorm.ConnPool, values map[string]interface{}, reset bool) error {
+	batch, vars := sessionContextSQL(values, reset, 0)
+	_, err := pool.ExecContext(ctx, batch, vars...)
+	return err
+}
+
+// withSessionContext runs exec with the session context of the statement context, set on the
+// transaction, on a connection pinned for the statement, or on a transaction begun for the
+// statement when the pool can't pin connections, which is reset before going back to the pool
+func withSessionContext(exec func(*gorm.DB)) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		values := sessionContextOf(db.Statement.Context)
+		if len(values) == 0 || db.Error != nil || db.DryRun {
+			exec(db)
+			return
+		}
+
+		if committer, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok && committer != nil {
+			if db.AddError(setSessionContext(db.Statement.Context, db.Statement.ConnPool, values, false)) == nil {
+				exec(db)
+			}
+			return
+		}
+
+		pool := db.Statement.ConnPool
+		if prepared, ok := pool.(*gorm.PreparedStmtDB); ok {
+			// the statements prepared on the pool don't run on the pinned connection
+			pool = prepared.ConnPool
+		}
+
+		switch beginner := pool.(type) {
+		case *sql.DB:
+			conn, err := beginner.Conn(db.Statement.Context)
+			if db.AddError(err) != nil {
+				return
+			}
+			defer conn.Close()
+
+			execPinned(db, conn, values, exec)
+		case gorm.TxBeginner:
+			tx, err := beginner.BeginTx(db.Statement.Context, nil)
+			if db.AddError(err) == nil {
+				execPinnedTx(db, tx, values, exec)
+			}
+		case gorm.ConnPoolBeginner:
+			tx, err := beginner.BeginTx(db.Statement.Context, nil)
+			if db.AddError(err) == nil {
+				execPinnedTx(db, tx, values, exec)
+			}
+		default:
+			db.AddError(fmt.Errorf("session context requires a pool able to pin connections or to begin transactions, got %T", pool))
+		}
+	}
+}
+
+// execPinned runs exec on conn with the session context set, resetting it afterwards
+func execPinned(db *gorm.DB, conn gorm.ConnPool, values map[string]interface{}, exec func(*gorm.DB)) {
+	if db.AddError(setSessionContext(db.Statement.Context, conn, values, false)) != nil {
+		return
+	}
+
+	pool := db.Statement.ConnPool
+	db.Statement.ConnPool = conn
+	exec(db)
+	db.Statement.ConnPool = pool
+
+	db.AddError(setSessionContext(db.Statement.Context, conn, values, true))
+}
+
+// execPinnedTx runs exec in tx with the session context set, committing it unless the statement
+// failed
+func execPinnedTx(db *gorm.DB, tx gorm.ConnPool, values map[string]interface{}, exec func(*gorm.DB)) {
+	committer, ok := tx.(gorm.TxCommitter)
+	if !ok {
+		db.AddError(fmt.Errorf("session context requires a transaction, got %T", tx))
+		return
+	}
+
+	execPinned(db, tx, values, exec)
+	if db.Error != nil {
+		committer.Rollback()
+		return
+	}
+	db.AddError(committer.Commit())
+}
+
+// rowWithSessionContext runs exec with the session context set by the batch of the statement,
+// the rows outlive the callback so the connection can't be pinned, and is reset by the driver
+// when it is reused from the pool
+func rowWithSessionContext(exec func(*gorm.DB)) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		values := sessionContextOf(db.Statement.Context)
+		if set, ok := db.InstanceGet(sessionContextSetKey); len(values) == 0 || db.Error != nil || db.DryRun || (ok && set.(bool)) {
+			exec(db)
+			return
+		}
+
+		if committer, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok && committer != nil {
+			if db.AddError(setSessionContext(db.Statement.Context, db.Statement.ConnPool, values, false)) == nil {
+				exec(db)
+			}
+			return
+		}
+
+		callbacks.BuildQuerySQL(db)
+		batch, vars := sessionContextSQL(values, false, len(db.Statement.Vars))
+		batch += db.Statement.SQL.String()
+		db.Statement.SQL.Reset()
+		db.Statement.SQL.WriteString(batch)
+		db.Statement.Vars = append(db.Statement.Vars, vars...)
+		db.InstanceSet(sessionContextSetKey, true)
+
+		exec(db)
+	}
+}
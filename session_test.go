@@ -0,0 +1,62 @@
+package sqlserver
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type sessionOrder struct {
+	ID       uint
+	TenantID uint
+}
+
+func TestSessionContext(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db, err := gorm.Open(New(Config{Conn: pool}), &gorm.Config{Logger: logger.Discard, PrepareStmt: true})
+	if err != nil {
+		t.Fatalf("failed to open db, got error %v", err)
+	}
+	pool.recorded()
+
+	ctx := WithSessionContext(context.Background(), map[string]interface{}{"tenant_id": 42})
+	if err := db.WithContext(ctx).Find(&[]sessionOrder{}).Error; err != nil {
+		t.Fatalf("failed to query with the session context, got error %v", err)
+	}
+
+	expects := []fakeStatement{
+		{query: "BEGIN TRANSACTION"},
+		{query: "EXEC sp_set_session_context @key = @p1, @value = @p2;", args: []interface{}{"tenant_id", 42}},
+		{query: `SELECT * FROM "session_orders"`},
+		{query: "EXEC sp_set_session_context @key = @p1, @value = @p2;", args: []interface{}{"tenant_id", nil}},
+		{query: "COMMIT TRANSACTION"},
+	}
+	if statements := pool.recordedStatements(); !reflect.DeepEqual(statements, expects) {
+		t.Errorf("expects the session context to be set in a transaction of the pool\nexpects: %v\ngot:     %v", expects, statements)
+	}
+
+	queryErr := errors.New("query failed")
+	pool.fail(nil, nil, queryErr)
+	if err := db.WithContext(ctx).Find(&[]sessionOrder{}).Error; !errors.Is(err, queryErr) {
+		t.Errorf("expects the error of the query, got %v", err)
+	}
+
+	if queries := pool.recorded(); len(queries) != 5 || queries[4] != "ROLLBACK TRANSACTION" {
+		t.Errorf("expects the transaction to be rolled back, got %v", queries)
+	}
+
+	db = openFake(t, Config{}, pool)
+	if err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Find(&[]sessionOrder{}).Error
+	}); err != nil {
+		t.Fatalf("failed to query in a transaction, got error %v", err)
+	}
+
+	if queries := pool.recorded(); len(queries) != 4 || queries[1] != "EXEC sp_set_session_context @key = @p1, @value = @p2;" || queries[3] != "COMMIT TRANSACTION" {
+		t.Errorf("expects the session context to be set on the transaction, got %v", queries)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	_ "github.com/denisenkom/go-mssqldb"
 	"gorm.io/gorm"
@@ -25,6 +26,16 @@ type Config struct {
 	Conn              gorm.ConnPool
 	ProductVersion    string
 	Edition           string
+	RetryPolicy       *RetryPolicy
+	BulkCopyThreshold int
+	BulkCopyOptions   BulkCopy
+	DefaultSchema     string
+	UseVarchar        bool
+	UseDatetime2      bool
+	UpsertStrategy    UpsertStrategy
+	OpenJSONThreshold int
+	ReadOnlyIntent    bool
+	ReadOnlyConn      gorm.ConnPool
 }
 
 type Dialector struct {
@@ -46,13 +57,33 @@ func New(config Config) gorm.Dialector {
 func (dialector Dialector) Initialize(db *gorm.DB) (err error) {
 
 	// register callbacks
-	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
-	db.Callback().Create().Replace("gorm:create", Create)
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{
+		QueryClauses:  []string{"SELECT", "FROM", "WHERE", "GROUP BY", "ORDER BY", "LIMIT", "FOR", "OPTION"},
+		UpdateClauses: []string{"UPDATE", "SET", "WHERE", "OPTION"},
+		DeleteClauses: []string{"DELETE", "FROM", "WHERE", "OPTION"},
+	})
+	db.Callback().Create().Replace("gorm:create", withSessionContext(Create))
+	db.Callback().Query().Replace("gorm:query", withSessionContext(callbacks.Query))
+	db.Callback().Update().Replace("gorm:update", withSessionContext(Update))
+	db.Callback().Delete().Replace("gorm:delete", withSessionContext(Delete))
+	db.Callback().Row().Replace("gorm:row", rowWithSessionContext(callbacks.RowQuery))
+	db.Callback().Raw().Replace("gorm:raw", withSessionContext(callbacks.RawExec))
+	registerRowVersion(db)
+	dialector.registerErrorTranslator(db)
+
+	if dialector.DefaultSchema != "" {
+		db.NamingStrategy = schemaNamer{Namer: db.NamingStrategy, Schema: dialector.DefaultSchema}
+	}
 
 	if dialector.DriverName == "" {
 		dialector.DriverName = "sqlserver"
 	}
 
+	// the readable secondary is connected to with the DSN of the primary
+	if dialector.ReadOnlyIntent && dialector.ReadOnlyConn == nil && dialector.DSN == "" {
+		return errors.New("ReadOnlyIntent requires the DSN of the primary, or a ReadOnlyConn, to connect to the readable secondary")
+	}
+
 	if dialector.Conn != nil {
 		db.ConnPool = dialector.Conn
 	} else {
@@ -62,6 +93,22 @@ func (dialector Dialector) Initialize(db *gorm.DB) (err error) {
 		}
 	}
 
+	// route the read-only queries to a readable secondary
+	if dialector.ReadOnlyConn != nil || dialector.ReadOnlyIntent {
+		router := &readOnlyRouter{primary: db.ConnPool, secondary: dialector.ReadOnlyConn}
+		if router.secondary == nil {
+			dsn, err := readOnlyDSN(dialector.DSN)
+			if err != nil {
+				return err
+			}
+
+			if router.secondary, err = sql.Open(dialector.DriverName, dsn); err != nil {
+				return err
+			}
+		}
+		router.register(db)
+	}
+
 	// retrieve the server version to determine if legacy queries should be used
 	var version, edition string
 	err = db.ConnPool.QueryRowContext(context.Background(), "SELECT SERVERPROPERTY('productversion') AS version, SERVERPROPERTY('Edition') AS edition;").Scan(&version, &edition)
@@ -70,7 +117,14 @@ func (dialector Dialector) Initialize(db *gorm.DB) (err error) {
 		return errors.New(fmt.Sprintf("unable to get server version with error: %s", err.Error()))
 	}
 
-	db.Logger.Info(context.Background(), fmt.Sprintf("found server with version: %s %s", edition, version))
+	// the isolation settings are only logged, reading sys.databases may not be permitted
+	var snapshotIsolation string
+	var readCommittedSnapshot bool
+	if err := db.ConnPool.QueryRowContext(context.Background(), "SELECT snapshot_isolation_state_desc, is_read_committed_snapshot_on FROM sys.databases WHERE name = DB_NAME();").Scan(&snapshotIsolation, &readCommittedSnapshot); err == nil {
+		db.Logger.Info(context.Background(), fmt.Sprintf("found server with version: %s %s, snapshot isolation: %s, read committed snapshot: %t", edition, version, snapshotIsolation, readCommittedSnapshot))
+	} else {
+		db.Logger.Info(context.Background(), fmt.Sprintf("found server with version: %s %s", edition, version))
+	}
 	dialector.ProductVersion = version
 	dialector.Edition = edition
 
@@ -84,11 +138,35 @@ func (dialector Dialector) Initialize(db *gorm.DB) (err error) {
 	return
 }
 
+// schemaNamer qualifies the table names of the naming strategy with Config.DefaultSchema
+type schemaNamer struct {
+	schema.Namer
+	Schema string
+}
+
+func (namer schemaNamer) TableName(table string) string {
+	return namer.qualify(namer.Namer.TableName(table))
+}
+
+func (namer schemaNamer) JoinTableName(joinTable string) string {
+	return namer.qualify(namer.Namer.JoinTableName(joinTable))
+}
+
+func (namer schemaNamer) qualify(table string) string {
+	if strings.Contains(table, ".") {
+		return table
+	}
+	return namer.Schema + "." + table
+}
+
 func (dialector Dialector) ClauseBuilders() map[string]clause.ClauseBuilder {
 	if dialector.IsUnsupportedSQLServer() {
 		return dialector.getUnsupportedClauses()
 	} else {
-		return map[string]clause.ClauseBuilder{
+		builders := map[string]clause.ClauseBuilder{
+			"FROM":   fromClauseBuilder,
+			"UPDATE": updateClauseBuilder,
+			"FOR":    forClauseBuilder,
 			"LIMIT": func(c clause.Clause, builder clause.Builder) {
 				if limit, ok := c.Expression.(clause.Limit); ok {
 					if stmt, ok := builder.(*gorm.Statement); ok {
@@ -120,6 +198,11 @@ func (dialector Dialector) ClauseBuilders() map[string]clause.ClauseBuilder {
 				}
 			},
 		}
+
+		if dialector.OpenJSONThreshold > 0 {
+			builders["WHERE"] = dialector.buildWhere
+		}
+		return builders
 	}
 }
 
@@ -128,8 +211,9 @@ func (dialector Dialector) DefaultValueOf(field *schema.Field) clause.Expression
 }
 
 func (dialector Dialector) Migrator(db *gorm.DB) gorm.Migrator {
+	// the catalog reads must see the schema changes, a readable secondary may lag behind
 	return Migrator{migrator.Migrator{Config: migrator.Config{
-		DB:                          db,
+		DB:                          db.Clauses(Primary()).Session(&gorm.Session{}),
 		Dialector:                   dialector,
 		CreateIndexAfterCreateTable: true,
 	}}}
@@ -179,6 +263,8 @@ func (dialector Dialector) DataTypeOf(field *schema.Field) string {
 	case schema.Int, schema.Uint:
 		var sqlType string
 		switch {
+		case field.DataType == schema.Uint && field.Size <= 8:
+			sqlType = "tinyint"
 		case field.Size < 16:
 			sqlType = "smallint"
 		case field.Size < 31:
@@ -192,6 +278,21 @@ func (dialector Dialector) DataTypeOf(field *schema.Field) string {
 		}
 		return sqlType
 	case schema.Float:
+		if field.Precision > 0 {
+			sqlType := "decimal"
+			if _, ok := field.TagSettings["NUMERIC"]; ok {
+				sqlType = "numeric"
+			}
+
+			if field.Scale > 0 {
+				return fmt.Sprintf("%s(%d,%d)", sqlType, field.Precision, field.Scale)
+			}
+			return fmt.Sprintf("%s(%d)", sqlType, field.Precision)
+		}
+
+		if field.Size == 32 {
+			return "real"
+		}
 		return "float"
 	case schema.String:
 		size := field.Size
@@ -203,27 +304,72 @@ func (dialector Dialector) DataTypeOf(field *schema.Field) string {
 				size = 256
 			}
 		}
-		if size > 0 && size <= 4000 {
-			return fmt.Sprintf("nvarchar(%d)", size)
+
+		sqlType, maxSize := "nvarchar", 4000
+		if _, ok := field.TagSettings["VARCHAR"]; ok || dialector.UseVarchar {
+			if _, ok := field.TagSettings["NVARCHAR"]; !ok {
+				sqlType, maxSize = "varchar", 8000
+			}
 		}
-		return "nvarchar(MAX)"
+
+		if size > 0 && size <= maxSize {
+			return fmt.Sprintf("%s(%d)", sqlType, size)
+		}
+		return sqlType + "(MAX)"
 	case schema.Time:
-		return "datetimeoffset"
+		sqlType := "datetimeoffset"
+		if dialector.UseDatetime2 {
+			sqlType = "datetime2"
+		}
+
+		if field.Precision > 0 && field.Precision <= 7 {
+			return fmt.Sprintf("%s(%d)", sqlType, field.Precision)
+		}
+		return sqlType
 	case schema.Bytes:
+		if field.Size > 0 && field.Size <= 8000 {
+			return fmt.Sprintf("varbinary(%d)", field.Size)
+		}
 		return "varbinary(MAX)"
 	}
 
 	return string(field.DataType)
 }
 
-func (dialectopr Dialector) SavePoint(tx *gorm.DB, name string) error {
-	tx.Exec("SAVE TRANSACTION " + name)
+// maxSavePointLength maximum length of savepoint names
+const maxSavePointLength = 32
+
+func validSavePoint(name string) error {
+	if name == "" || utf8.RuneCountInString(name) > maxSavePointLength || strings.ContainsAny(name, `".`) {
+		return fmt.Errorf("%w: %q", ErrInvalidSavePoint, name)
+	}
 	return nil
 }
 
+func (dialectopr Dialector) SavePoint(tx *gorm.DB, name string) error {
+	if err := validSavePoint(name); err != nil {
+		return err
+	}
+	return tx.Exec("SAVE TRANSACTION ?", clause.Column{Name: name}).Error
+}
+
+// RollbackTo rolls back to the savepoint name, unless the transaction is uncommittable which
+// only allows rolling back the whole transaction
 func (dialectopr Dialector) RollbackTo(tx *gorm.DB, name string) error {
-	tx.Exec("ROLLBACK TRANSACTION " + name)
-	return nil
+	if err := validSavePoint(name); err != nil {
+		return err
+	}
+
+	if !tx.DryRun {
+		var state int
+		if err := tx.Raw("SELECT XACT_STATE()").Row().Scan(&state); err != nil {
+			return err
+		} else if state == -1 {
+			return ErrUncommittableTransaction
+		}
+	}
+
+	return tx.Exec("ROLLBACK TRANSACTION ?", clause.Column{Name: name}).Error
 }
 
 type Edition int
@@ -357,12 +503,11 @@ func (Dialector) getUnsupportedClauses() map[string]clause.ClauseBuilder {
 								builder.WriteString("(SELECT NULL) ")
 							}
 						}
-						builder.WriteString(") AS row ")
-						if from, ok := stmt.Clauses["FROM"]; ok {
-							from.Build(builder)
+						builder.WriteString(") AS row FROM ")
+						if from, ok := stmt.Clauses["FROM"].Expression.(clause.From); ok {
+							buildFrom(stmt, from)
 						} else {
-							builder.WriteString("FROM ")
-							builder.WriteQuoted(stmt.Table)
+							buildFrom(stmt, clause.From{})
 						}
 
 						builder.WriteString(") a")
@@ -394,8 +539,14 @@ func (Dialector) getUnsupportedClauses() map[string]clause.ClauseBuilder {
 				}
 			}
 
-			c.Expression.(clause.From).Build(builder)
+			if stmt, ok := builder.(*gorm.Statement); ok {
+				buildFrom(stmt, c.Expression.(clause.From))
+			} else {
+				c.Expression.(clause.From).Build(builder)
+			}
 		},
+		"UPDATE": updateClauseBuilder,
+		"FOR":    forClauseBuilder,
 		"LIMIT": func(c clause.Clause, builder clause.Builder) {
 			// handled by the from function
 		},
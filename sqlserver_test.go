@@ -0,0 +1,86 @@
+package sqlserver
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	"gorm.io/gorm"
+)
+
+func TestSavePoint(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db := openFake(t, Config{}, pool)
+
+	tx := db.Begin()
+	for _, name := range []string{"", "a.b", `a"b`, strings.Repeat("a", 33)} {
+		if err := tx.SavePoint(name).Error; !errors.Is(err, ErrInvalidSavePoint) {
+			t.Errorf("expects savepoint %q to be rejected, got %v", name, err)
+		}
+		tx.Error = nil
+	}
+
+	if err := tx.SavePoint("before_update").Error; err != nil {
+		t.Fatalf("failed to save transaction, got error %v", err)
+	}
+
+	pool.fail(mssql.Error{Number: 3902, Message: "The SAVE TRANSACTION request has no corresponding BEGIN TRANSACTION"})
+	if err := tx.SavePoint("failing").Error; err == nil {
+		t.Errorf("expects the error of SAVE TRANSACTION to be returned")
+	}
+	tx.Error = nil
+
+	// the transaction is committable
+	pool.returns([]string{"state"}, [][]driver.Value{{int64(1)}})
+	if err := tx.RollbackTo("before_update").Error; err != nil {
+		t.Fatalf("failed to roll back to the savepoint, got error %v", err)
+	}
+
+	// the transaction is doomed
+	pool.returns([]string{"state"}, [][]driver.Value{{int64(-1)}})
+	if err := tx.RollbackTo("before_update").Error; !errors.Is(err, ErrUncommittableTransaction) {
+		t.Errorf("expects ErrUncommittableTransaction, got %v", err)
+	}
+	tx.Rollback()
+
+	expects := []string{
+		"BEGIN TRANSACTION",
+		`SAVE TRANSACTION "before_update"`,
+		`SAVE TRANSACTION "failing"`,
+		"SELECT XACT_STATE()",
+		`ROLLBACK TRANSACTION "before_update"`,
+		"SELECT XACT_STATE()",
+		"ROLLBACK TRANSACTION",
+	}
+	if queries := pool.recorded(); strings.Join(queries, "\n") != strings.Join(expects, "\n") {
+		t.Errorf("expects statements %v, got %v", expects, queries)
+	}
+}
+
+func TestNestedTransactionRollsBackToSavePoint(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db := openFake(t, Config{}, pool)
+
+	pool.returns([]string{"state"}, [][]driver.Value{{int64(1)}})
+
+	nestedErr := errors.New("nested")
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Transaction(func(tx *gorm.DB) error {
+			tx.Exec("UPDATE users SET name = ?", "nested")
+			return nestedErr
+		}); err != nestedErr {
+			t.Errorf("expects the error of the nested transaction, got %v", err)
+		}
+		return tx.Exec("UPDATE users SET age = ?", 18).Error
+	}); err != nil {
+		t.Fatalf("failed to commit the outer transaction, got error %v", err)
+	}
+
+	queries := pool.recorded()
+	if len(queries) != 7 || !strings.HasPrefix(queries[1], `SAVE TRANSACTION "sp`) || queries[3] != "SELECT XACT_STATE()" ||
+		!strings.HasPrefix(queries[4], `ROLLBACK TRANSACTION "sp`) || queries[5] != "UPDATE users SET age = @p1" || queries[6] != "COMMIT TRANSACTION" {
+		t.Errorf("expects the nested transaction to be rolled back to its savepoint, got %v", queries)
+	}
+}
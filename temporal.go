@@ -0,0 +1,119 @@
+package sqlserver
+
+import (
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// TemporalTable models implementing it are created as system-versioned temporal tables by
+// Migrator.CreateTable, with the ValidFrom and ValidTo period columns, hidden unless the model
+// has the fields, and the history table HistoryTable, <table>_history when empty
+//
+//	func (User) HistoryTable() string { return "users_history" }
+type TemporalTable interface {
+	HistoryTable() string
+}
+
+// temporalTableOf returns the model of s implementing TemporalTable
+func temporalTableOf(s *schema.Schema) (TemporalTable, bool) {
+	if s == nil || s.ModelType == nil {
+		return nil, false
+	}
+	temporal, ok := reflect.New(s.ModelType).Interface().(TemporalTable)
+	return temporal, ok
+}
+
+// periodColumns returns the names of the ValidFrom and ValidTo period columns
+func periodColumns(namer schema.Namer) (start, end string) {
+	return namer.ColumnName("", "ValidFrom"), namer.ColumnName("", "ValidTo")
+}
+
+// omitPeriodColumns period columns are generated by the database and can't be written
+func omitPeriodColumns(db *gorm.DB) {
+	if _, ok := temporalTableOf(db.Statement.Schema); ok {
+		start, end := periodColumns(db.NamingStrategy)
+		db.Statement.Omits = append(db.Statement.Omits, start, end)
+	}
+}
+
+// SystemTime queries the rows of a temporal table valid at a point or during a period of time,
+// written after the table or after the joined table or alias Table
+//
+//	db.Clauses(sqlserver.ForSystemTimeAsOf(yesterday)).First(&user)
+//	db.Joins("Company").Clauses(sqlserver.ForSystemTimeAll().For("Company")).Find(&users)
+type SystemTime struct {
+	Table string
+	SQL   string
+	Vars  []interface{}
+}
+
+// ForSystemTimeAsOf rows valid at t
+func ForSystemTimeAsOf(t time.Time) SystemTime {
+	return SystemTime{SQL: "AS OF ?", Vars: []interface{}{t.UTC()}}
+}
+
+// ForSystemTimeBetween rows valid at any time from from to to, including the rows becoming valid at to
+func ForSystemTimeBetween(from, to time.Time) SystemTime {
+	return SystemTime{SQL: "BETWEEN ? AND ?", Vars: []interface{}{from.UTC(), to.UTC()}}
+}
+
+// ForSystemTimeContainedIn rows becoming valid and ceasing to be valid between from and to
+func ForSystemTimeContainedIn(from, to time.Time) SystemTime {
+	return SystemTime{SQL: "CONTAINED IN (?, ?)", Vars: []interface{}{from.UTC(), to.UTC()}}
+}
+
+// ForSystemTimeAll current and history rows
+func ForSystemTimeAll() SystemTime {
+	return SystemTime{SQL: "ALL"}
+}
+
+// For applies the period to the joined table or alias table
+func (systemTime SystemTime) For(table string) SystemTime {
+	systemTime.Table = table
+	return systemTime
+}
+
+func (SystemTime) Name() string {
+	return "FOR SYSTEM_TIME"
+}
+
+func (systemTime SystemTime) Build(builder clause.Builder) {
+	builder.WriteString("FOR SYSTEM_TIME ")
+	clause.Expr{SQL: systemTime.SQL, Vars: systemTime.Vars}.Build(builder)
+}
+
+func (systemTime SystemTime) MergeClause(c *clause.Clause) {
+	all, _ := c.Expression.(systemTimeList)
+	c.Expression = append(all[:len(all):len(all)], systemTime)
+}
+
+// systemTimeList periods of the tables of a statement, built by the FROM clause builder
+type systemTimeList []SystemTime
+
+func (systemTimeList) Build(clause.Builder) {}
+
+func hasSystemTime(stmt *gorm.Statement) bool {
+	_, ok := stmt.Clauses["FOR SYSTEM_TIME"]
+	return ok
+}
+
+// writeSystemTime writes the period of table, if any, the last one applying to table wins
+func writeSystemTime(stmt *gorm.Statement, table clause.Table, main bool) {
+	c, ok := stmt.Clauses["FOR SYSTEM_TIME"]
+	if !ok {
+		return
+	}
+
+	all, _ := c.Expression.(systemTimeList)
+	for idx := len(all) - 1; idx >= 0; idx-- {
+		if tableMatches(stmt, table, main, all[idx].Table) {
+			stmt.WriteByte(' ')
+			all[idx].Build(stmt)
+			return
+		}
+	}
+}
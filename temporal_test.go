@@ -0,0 +1,104 @@
+package sqlserver
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+type temporalAccount struct {
+	ID   uint
+	Name string
+}
+
+func (temporalAccount) HistoryTable() string { return "" }
+
+type temporalPayment struct {
+	ID     uint
+	Amount int
+}
+
+func (temporalPayment) HistoryTable() string { return "archive.payments_history" }
+
+func TestTemporalTableMigration(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db := openFake(t, Config{}, pool)
+
+	pool.returns([]string{"schema"}, [][]driver.Value{{"dbo"}})
+	if err := db.Migrator().CreateTable(&temporalAccount{}); err != nil {
+		t.Fatalf("failed to create temporal table, got error %v", err)
+	}
+
+	expects := []string{
+		`CREATE TABLE "temporal_accounts" ("id" bigint IDENTITY(1,1),"name" nvarchar(MAX),PRIMARY KEY ("id"))`,
+		"SELECT SCHEMA_NAME()",
+		"BEGIN TRANSACTION",
+		`ALTER TABLE "temporal_accounts" ADD "valid_from" datetime2 GENERATED ALWAYS AS ROW START HIDDEN NOT NULL CONSTRAINT "DF_temporal_accounts_valid_from" DEFAULT SYSUTCDATETIME(), ` +
+			`"valid_to" datetime2 GENERATED ALWAYS AS ROW END HIDDEN NOT NULL CONSTRAINT "DF_temporal_accounts_valid_to" DEFAULT CONVERT(datetime2, '9999-12-31 23:59:59.9999999'), ` +
+			`PERIOD FOR SYSTEM_TIME ("valid_from", "valid_to")`,
+		`ALTER TABLE "temporal_accounts" SET (SYSTEM_VERSIONING = ON (HISTORY_TABLE = "dbo"."temporal_accounts_history"))`,
+		"COMMIT TRANSACTION",
+	}
+	if queries := pool.recorded(); !reflect.DeepEqual(queries, expects) {
+		t.Errorf("expects the system versioning to be turned on\nexpects: %v\ngot:     %v", expects, queries)
+	}
+
+	if err := db.Migrator().CreateTable(&temporalPayment{}); err != nil {
+		t.Fatalf("failed to create temporal table, got error %v", err)
+	}
+
+	if queries := pool.recorded(); len(queries) != 5 ||
+		queries[3] != `ALTER TABLE "temporal_payments" SET (SYSTEM_VERSIONING = ON (HISTORY_TABLE = "archive"."payments_history"))` {
+		t.Errorf("expects the history table of the model, got %v", queries)
+	}
+
+	// the default schema can't be looked up
+	if err := db.Migrator().CreateTable(&temporalAccount{}); err == nil {
+		t.Errorf("expects the error looking up the schema of the history table")
+	}
+
+	if queries := pool.recorded(); len(queries) != 2 || queries[1] != "SELECT SCHEMA_NAME()" {
+		t.Errorf("expects the system versioning not to be turned on, got %v", queries)
+	}
+}
+
+func TestDropTemporalTable(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db := openFake(t, Config{}, pool)
+
+	pool.returns([]string{"name", "parent"})
+	pool.returns([]string{"schema"}, [][]driver.Value{{"dbo"}})
+	pool.returns([]string{"type"}, [][]driver.Value{{int64(2)}})
+	if err := db.Migrator().DropTable(&temporalAccount{}); err != nil {
+		t.Fatalf("failed to drop temporal table, got error %v", err)
+	}
+
+	expects := []string{
+		`SELECT name, OBJECT_SCHEMA_NAME(parent_object_id) + '.' + OBJECT_NAME(parent_object_id) as parent FROM sys.foreign_keys WHERE referenced_object_id = object_id(@p1)`,
+		"SELECT SCHEMA_NAME()",
+		"SELECT OBJECTPROPERTY(OBJECT_ID(@p1), 'TableTemporalType')",
+		`ALTER TABLE "temporal_accounts" SET (SYSTEM_VERSIONING = OFF)`,
+		`DROP TABLE IF EXISTS "temporal_accounts"`,
+		`DROP TABLE IF EXISTS "dbo"."temporal_accounts_history"`,
+	}
+	if queries := pool.recorded(); !reflect.DeepEqual(queries, expects) {
+		t.Errorf("expects the table and its history table to be dropped\nexpects: %v\ngot:     %v", expects, queries)
+	}
+
+	// the system versioning was turned off already
+	pool.returns([]string{"name", "parent"})
+	pool.returns([]string{"type"}, [][]driver.Value{{int64(0)}})
+	if err := db.Migrator().DropTable(&temporalPayment{}); err != nil {
+		t.Fatalf("failed to drop temporal table, got error %v", err)
+	}
+
+	expects = []string{
+		`SELECT name, OBJECT_SCHEMA_NAME(parent_object_id) + '.' + OBJECT_NAME(parent_object_id) as parent FROM sys.foreign_keys WHERE referenced_object_id = object_id(@p1)`,
+		"SELECT OBJECTPROPERTY(OBJECT_ID(@p1), 'TableTemporalType')",
+		`DROP TABLE IF EXISTS "temporal_payments"`,
+		`DROP TABLE IF EXISTS "archive"."payments_history"`,
+	}
+	if queries := pool.recorded(); !reflect.DeepEqual(queries, expects) {
+		t.Errorf("expects the table and its history table to be dropped\nexpects: %v\ngot:     %v", expects, queries)
+	}
+}
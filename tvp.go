@@ -0,0 +1,68 @@
+package sqlserver
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"time"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	"gorm.io/gorm/clause"
+)
+
+// TVP binds values as a table-valued parameter of the user-defined table type typeName, written as
+// a derived table to be used by IN conditions, joins and raw queries, values are a slice of structs
+// matching the columns of the type or a slice of scalars for single column types
+//
+//	db.Where("id IN ?", sqlserver.TVP("dbo.IntList", ids))
+//	db.Joins("JOIN ? AS ids ON ids.id = users.id", sqlserver.TVP("dbo.IntList", ids))
+//	db.Raw("SELECT * FROM ? AS ids", sqlserver.TVP("dbo.IntList", ids))
+func TVP(typeName string, values interface{}) clause.Expr {
+	return clause.Expr{
+		SQL:  "(SELECT * FROM ?)",
+		Vars: []interface{}{mssql.TVP{TypeName: typeName, Value: tvpRows(values)}},
+	}
+}
+
+// tvpRows converts values to the slice of structs required by mssql.TVP
+func tvpRows(values interface{}) interface{} {
+	rv := reflect.ValueOf(values)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return values
+	}
+
+	elemType := rv.Type().Elem()
+	switch {
+	case isRowType(elemType) && rv.Kind() == reflect.Slice:
+		return values
+	case elemType.Kind() == reflect.Ptr && isRowType(elemType.Elem()):
+		rows := reflect.MakeSlice(reflect.SliceOf(elemType.Elem()), 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			if elem := rv.Index(i); !elem.IsNil() {
+				rows = reflect.Append(rows, elem.Elem())
+			}
+		}
+		return rows.Interface()
+	case isRowType(elemType):
+		rows := reflect.MakeSlice(reflect.SliceOf(elemType), rv.Len(), rv.Len())
+		reflect.Copy(rows, rv)
+		return rows.Interface()
+	}
+
+	rowType := reflect.StructOf([]reflect.StructField{{Name: "Value", Type: elemType}})
+	rows := reflect.MakeSlice(reflect.SliceOf(rowType), rv.Len(), rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		rows.Index(i).Field(0).Set(rv.Index(i))
+	}
+	return rows.Interface()
+}
+
+// isRowType returns whether t is a struct of columns, time.Time and driver.Valuer structs such as
+// sql.NullString are scalars
+func isRowType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && !t.Implements(valuerType) && !reflect.PtrTo(t).Implements(valuerType)
+}
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
@@ -0,0 +1,72 @@
+package sqlserver
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestTVPRows(t *testing.T) {
+	now := time.Now()
+	rows := reflect.ValueOf(tvpRows([]time.Time{now}))
+	if rows.Type().Elem().Kind() != reflect.Struct || rows.Type().Elem().NumField() != 1 || rows.Index(0).Field(0).Interface() != now {
+		t.Errorf("expects times to be bound as a single column, got %v", rows.Type())
+	}
+
+	rows = reflect.ValueOf(tvpRows([]sql.NullString{{String: "a", Valid: true}}))
+	if rows.Type().Elem().NumField() != 1 || rows.Type().Elem().Field(0).Type != reflect.TypeOf(sql.NullString{}) {
+		t.Errorf("expects valuers to be bound as a single column, got %v", rows.Type())
+	}
+
+	type price struct {
+		ID    uint
+		Price float64
+	}
+	if rows := tvpRows([]*price{{ID: 1}, nil}); !reflect.DeepEqual(rows, []price{{ID: 1}}) {
+		t.Errorf("expects pointers to structs to be dereferenced, got %#v", rows)
+	}
+}
+
+type tableTypePrice struct {
+	ID      uint
+	Price   float64 `gorm:"not null"`
+	Comment string  `tvp:"-"`
+}
+
+type tableTypeModel struct {
+	gorm.Model
+	Price float64
+}
+
+type tableTypeIgnored struct {
+	ID    uint
+	Price float64
+	Note  string `gorm:"-"`
+}
+
+func TestCreateTableType(t *testing.T) {
+	pool := newFakeConnPool(t)
+	db := openFake(t, Config{}, pool)
+
+	if err := db.Migrator().(Migrator).CreateTableType("PriceList", &tableTypePrice{}); err != nil {
+		t.Fatalf("failed to create table type, got error %v", err)
+	}
+
+	queries := pool.recorded()
+	if expects := `CREATE TYPE "PriceList" AS TABLE ("id" bigint,"price" float NOT NULL)`; len(queries) != 1 || queries[0] != expects {
+		t.Errorf("expects %v, got %v", expects, queries)
+	}
+
+	for _, value := range []interface{}{&tableTypeModel{}, &tableTypeIgnored{}} {
+		if err := db.Migrator().(Migrator).CreateTableType("PriceList", value); err == nil {
+			t.Errorf("expects %T to be rejected, as TVP binds its fields differently", value)
+		}
+	}
+
+	if queries := pool.recorded(); len(queries) != 0 {
+		t.Errorf("expects no table type to be created, got %v", queries)
+	}
+}
@@ -0,0 +1,135 @@
+package sqlserver
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+)
+
+func Update(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+
+	if db.Statement.Schema != nil && !db.Statement.Unscoped {
+		for _, c := range db.Statement.Schema.UpdateClauses {
+			db.Statement.AddClause(c)
+		}
+	}
+
+	var output clause.Expression
+	if db.Statement.SQL.String() == "" {
+		db.Statement.SQL.Grow(180)
+		db.Statement.AddClauseIfNotExists(clause.Update{})
+		omitPeriodColumns(db)
+		if set := callbacks.ConvertToAssignments(db.Statement); len(set) != 0 {
+			db.Statement.AddClause(set)
+		} else {
+			return
+		}
+
+		if output = outputOf(db, "INSERTED"); output != nil {
+			buildWithOutput(db.Statement, output)
+		} else {
+			db.Statement.Build(db.Statement.BuildClauses...)
+		}
+	}
+
+	if _, ok := db.Statement.Clauses["WHERE"]; !db.AllowGlobalUpdate && !ok {
+		db.AddError(gorm.ErrMissingWhereClause)
+		return
+	}
+
+	if !db.DryRun && db.Error == nil {
+		if output != nil {
+			queryOutput(db)
+		} else {
+			result, err := db.Statement.ConnPool.ExecContext(db.Statement.Context, db.Statement.SQL.String(), db.Statement.Vars...)
+
+			if err == nil {
+				db.RowsAffected, _ = result.RowsAffected()
+			} else {
+				db.AddError(err)
+			}
+		}
+	}
+}
+
+// outputOf returns the OUTPUT clause of clause.Returning, updates of a single model always
+// return its rowversion
+func outputOf(db *gorm.DB, table string) clause.Expression {
+	var (
+		c, hasReturning = db.Statement.Clauses["RETURNING"]
+		returning, _    = c.Expression.(clause.Returning)
+		columns         = returning.Columns
+	)
+
+	if table == "INSERTED" && db.Statement.ReflectValue.Kind() == reflect.Struct && db.Statement.ReflectValue.CanAddr() {
+		if field := rowVersionField(db.Statement.Schema); field != nil && (!hasReturning || len(columns) > 0) {
+			hasReturning = true
+			columns = append(columns[:len(columns):len(columns)], clause.Column{Name: field.DBName})
+		}
+	}
+
+	if !hasReturning {
+		return nil
+	}
+
+	if len(columns) == 0 {
+		return clause.Expr{SQL: "OUTPUT " + table + ".*"}
+	}
+
+	expr := clause.Expr{SQL: "OUTPUT "}
+	for idx, column := range columns {
+		if idx > 0 {
+			expr.SQL += ","
+		}
+		expr.SQL += table + ".?"
+		expr.Vars = append(expr.Vars, clause.Column{Name: column.Name})
+	}
+	return expr
+}
+
+// buildWithOutput builds the statement clauses with the OUTPUT clause in front of WHERE
+func buildWithOutput(stmt *gorm.Statement, output clause.Expression) {
+	clauses := stmt.BuildClauses
+	for idx, name := range clauses {
+		if name == "WHERE" {
+			clauses = stmt.BuildClauses[:idx]
+			break
+		}
+	}
+
+	stmt.Build(clauses...)
+	stmt.WriteByte(' ')
+	output.Build(stmt)
+
+	if len(clauses) < len(stmt.BuildClauses) {
+		stmt.WriteByte(' ')
+		stmt.Build(stmt.BuildClauses[len(clauses):]...)
+	}
+}
+
+// queryOutput executes the statement, scanning the rows of the OUTPUT clause into the model
+func queryOutput(db *gorm.DB) {
+	rows, err := db.Statement.ConnPool.QueryContext(db.Statement.Context, db.Statement.SQL.String(), db.Statement.Vars...)
+	if db.AddError(err) != nil {
+		return
+	}
+	defer rows.Close()
+
+	if db.Statement.ReflectValue.CanAddr() {
+		dest := db.Statement.Dest
+		db.Statement.Dest = db.Statement.ReflectValue.Addr().Interface()
+		gorm.Scan(rows, db, false)
+		db.Statement.Dest = dest
+	}
+
+	// a single model only receives the first row
+	for rows.Next() {
+		db.RowsAffected++
+	}
+	db.AddError(rows.Err())
+}
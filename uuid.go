@@ -0,0 +1,103 @@
+package sqlserver
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// UUID uniqueidentifier value in RFC 4122 byte order, SQL Server stores the first three groups
+// little-endian, which is swapped when scanning and writing values
+//
+//	type User struct {
+//		ID   sqlserver.UUID `gorm:"primaryKey;default:NEWSEQUENTIALID()"`
+//		Name string
+//	}
+type UUID [16]byte
+
+// ParseUUID parses the canonical xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx form, with or without braces
+func ParseUUID(s string) (u UUID, err error) {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("invalid UUID %q", s)
+	}
+
+	if _, err = hex.Decode(u[:], []byte(s[0:8]+s[9:13]+s[14:18]+s[19:23]+s[24:])); err != nil {
+		return u, fmt.Errorf("invalid UUID %q: %w", s, err)
+	}
+	return u, nil
+}
+
+func swapUUIDBytes(b []byte) {
+	b[0], b[1], b[2], b[3] = b[3], b[2], b[1], b[0]
+	b[4], b[5] = b[5], b[4]
+	b[6], b[7] = b[7], b[6]
+}
+
+func (u *UUID) Scan(value interface{}) (err error) {
+	switch v := value.(type) {
+	case nil:
+		*u = UUID{}
+	case []byte:
+		if len(v) == 36 {
+			*u, err = ParseUUID(string(v))
+			return
+		} else if len(v) != 16 {
+			return fmt.Errorf("invalid uniqueidentifier length %d", len(v))
+		}
+
+		copy(u[:], v)
+		swapUUIDBytes(u[:])
+	case string:
+		*u, err = ParseUUID(v)
+	default:
+		return fmt.Errorf("failed to scan %T into UUID", value)
+	}
+	return
+}
+
+func (u UUID) Value() (driver.Value, error) {
+	b := make([]byte, 16)
+	copy(b, u[:])
+	swapUUIDBytes(b)
+	return b, nil
+}
+
+func (u UUID) IsZero() bool {
+	return u == UUID{}
+}
+
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *UUID) UnmarshalText(text []byte) (err error) {
+	*u, err = ParseUUID(string(text))
+	return
+}
+
+func (UUID) GormDataType() string {
+	return "uniqueidentifier"
+}
+
+func (UUID) GormDBDataType(*gorm.DB, *schema.Field) string {
+	return "uniqueidentifier"
+}